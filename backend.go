@@ -0,0 +1,37 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+)
+
+type (
+	WindowOptions struct {
+		Title         string
+		Width, Height int
+		X, Y          int
+	}
+	// Window presents canvas frames and delivers golang.org/x/mobile events
+	// (lifecycle, key, mouse and size) to the Drawlib event loop.
+	Window interface {
+		NextEvent() interface{}
+		Send(event interface{})
+		Fill(dr image.Rectangle, c color.Color)
+		Present(src *image.RGBA, dr image.Rectangle)
+		SetMaximize(maximize bool)
+		SetFullScreen(fullscreen bool)
+		SetSize(width, height int)
+		SetLocation(x, y, width, height int)
+		Release()
+	}
+	// Backend opens a Window and runs f on it, returning when f returns.
+	Backend interface {
+		Main(o WindowOptions, f func(Window)) error
+	}
+	// steppedBackend is a Backend that may have no wall clock. When
+	// steppedClock reports true its frames run only when it steps them.
+	steppedBackend interface {
+		Backend
+		steppedClock() bool
+	}
+)
@@ -0,0 +1,159 @@
+package drawlib
+
+import "math"
+
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendColorDodge
+	BlendColorBurn
+	BlendHardLight
+	BlendSoftLight
+	BlendDifference
+	BlendExclusion
+	BlendHue
+	BlendSaturation
+	BlendColor
+	BlendLuminosity
+)
+
+var blendModeNames = [...]string{
+	"normal", "multiply", "screen", "overlay", "darken", "lighten",
+	"color-dodge", "color-burn", "hard-light", "soft-light", "difference",
+	"exclusion", "hue", "saturation", "color", "luminosity",
+}
+
+// String returns the CSS name of the mode, as used by mix-blend-mode.
+func (m BlendMode) String() string {
+	if m < 0 || int(m) >= len(blendModeNames) {
+		return "normal"
+	}
+	return blendModeNames[m]
+}
+
+// SetBlendMode sets how the colors of fills, strokes, text and images are
+// mixed with the colors already on the canvas.
+func (c *Canvas) SetBlendMode(mode BlendMode) *Canvas {
+	c.blendMode = mode
+	return c
+}
+
+// blendColors returns B(cb, cs) for the mode, with cb the backdrop and cs
+// the source color, as defined by the W3C compositing specification.
+func blendColors(mode BlendMode, cb, cs [3]float64) [3]float64 {
+	switch mode {
+	case BlendHue:
+		return setLum(setSat(cs, sat(cb)), lum(cb))
+	case BlendSaturation:
+		return setLum(setSat(cb, sat(cs)), lum(cb))
+	case BlendColor:
+		return setLum(cs, lum(cb))
+	case BlendLuminosity:
+		return setLum(cb, lum(cs))
+	}
+	var result [3]float64
+	for k := range result {
+		result[k] = blendChannel(mode, cb[k], cs[k])
+	}
+	return result
+}
+
+func blendChannel(mode BlendMode, b, s float64) float64 {
+	switch mode {
+	case BlendMultiply:
+		return b * s
+	case BlendScreen:
+		return b + s - b*s
+	case BlendOverlay:
+		return blendChannel(BlendHardLight, s, b)
+	case BlendDarken:
+		return math.Min(b, s)
+	case BlendLighten:
+		return math.Max(b, s)
+	case BlendColorDodge:
+		if b == 0 {
+			return 0
+		}
+		if s >= 1 {
+			return 1
+		}
+		return math.Min(1, b/(1-s))
+	case BlendColorBurn:
+		if b >= 1 {
+			return 1
+		}
+		if s <= 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-b)/s)
+	case BlendHardLight:
+		if s <= 0.5 {
+			return b * 2 * s
+		}
+		return blendChannel(BlendScreen, b, 2*s-1)
+	case BlendSoftLight:
+		if s <= 0.5 {
+			return b - (1-2*s)*b*(1-b)
+		}
+		d := math.Sqrt(b)
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		}
+		return b + (2*s-1)*(d-b)
+	case BlendDifference:
+		return math.Abs(b - s)
+	case BlendExclusion:
+		return b + s - 2*b*s
+	}
+	return s
+}
+
+func lum(c [3]float64) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func setLum(c [3]float64, l float64) [3]float64 {
+	d := l - lum(c)
+	c = [3]float64{c[0] + d, c[1] + d, c[2] + d}
+	l = lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for k := range c {
+		if n < 0 {
+			c[k] = l + (c[k]-l)*l/(l-n)
+		}
+		if x > 1 {
+			c[k] = l + (c[k]-l)*(1-l)/(x-l)
+		}
+	}
+	return c
+}
+
+func sat(c [3]float64) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+func setSat(c [3]float64, s float64) [3]float64 {
+	max, mid, min := 0, 1, 2
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	if c[mid] < c[min] {
+		mid, min = min, mid
+	}
+	if c[max] < c[mid] {
+		max, mid = mid, max
+	}
+	var result [3]float64
+	if c[max] > c[min] {
+		result[mid] = (c[mid] - c[min]) * s / (c[max] - c[min])
+		result[max] = s
+	}
+	return result
+}
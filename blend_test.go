@@ -0,0 +1,101 @@
+package drawlib
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestBlendChannel(t *testing.T) {
+	for _, test := range []struct {
+		mode    BlendMode
+		b, s, r float64
+	}{
+		{BlendNormal, 0.2, 0.7, 0.7},
+		{BlendMultiply, 0.5, 0.5, 0.25},
+		{BlendScreen, 0.5, 0.5, 0.75},
+		{BlendOverlay, 0.25, 0.5, 0.25},
+		{BlendOverlay, 0.75, 0.5, 0.75},
+		{BlendDarken, 0.3, 0.6, 0.3},
+		{BlendLighten, 0.3, 0.6, 0.6},
+		{BlendColorDodge, 0.5, 0.5, 1},
+		{BlendColorDodge, 0.25, 0.5, 0.5},
+		{BlendColorDodge, 0, 1, 0},
+		{BlendColorBurn, 0.5, 0.5, 0},
+		{BlendColorBurn, 0.75, 0.5, 0.5},
+		{BlendColorBurn, 1, 0, 1},
+		{BlendHardLight, 0.5, 0.25, 0.25},
+		{BlendHardLight, 0.5, 0.75, 0.75},
+		{BlendSoftLight, 0.5, 0.25, 0.375},
+		{BlendSoftLight, 0.25, 0.75, 0.375},
+		{BlendSoftLight, 0.16, 1, 0.398336},
+		{BlendDifference, 0.2, 0.7, 0.5},
+		{BlendExclusion, 0.5, 0.5, 0.5},
+	} {
+		if got := blendChannel(test.mode, test.b, test.s); math.Abs(got-test.r) > 1e-9 {
+			t.Errorf("%v(%v, %v) = %v, want %v", test.mode, test.b, test.s, got, test.r)
+		}
+	}
+}
+
+func TestBlendNonSeparable(t *testing.T) {
+	cb := [3]float64{0.8, 0.4, 0.2}
+	cs := [3]float64{0.1, 0.3, 0.9}
+	for _, test := range []struct {
+		mode     BlendMode
+		lum, sat float64
+	}{
+		{BlendHue, lum(cb), sat(cb)},
+		{BlendSaturation, lum(cb), sat(cs)},
+		{BlendColor, lum(cb), -1},
+		{BlendLuminosity, lum(cs), -1},
+	} {
+		got := blendColors(test.mode, cb, cs)
+		if math.Abs(lum(got)-test.lum) > 1e-9 {
+			t.Errorf("%v: luminosity %v, want %v", test.mode, lum(got), test.lum)
+		}
+		if test.sat >= 0 && math.Abs(sat(got)-test.sat) > 1e-9 {
+			t.Errorf("%v: saturation %v, want %v", test.mode, sat(got), test.sat)
+		}
+		for _, v := range got {
+			if v < 0 || v > 1 {
+				t.Errorf("%v: %v out of gamut", test.mode, got)
+			}
+		}
+	}
+	gray := [3]float64{0.5, 0.5, 0.5}
+	if got := blendColors(BlendHue, gray, cs); sat(got) > 1e-9 || math.Abs(lum(got)-0.5) > 1e-9 {
+		t.Errorf("hue onto gray is %v, want gray", got)
+	}
+}
+
+func TestBlendModeFill(t *testing.T) {
+	for _, test := range []struct {
+		mode BlendMode
+		want color.RGBA
+	}{
+		{BlendNormal, color.RGBA{128, 128, 128, 255}},
+		{BlendMultiply, color.RGBA{128, 0, 0, 255}},
+		{BlendScreen, color.RGBA{255, 128, 128, 255}},
+		{BlendDifference, color.RGBA{127, 128, 128, 255}},
+	} {
+		c := NewCanvas(4, 4)
+		c.Background(255, 0, 0)
+		c.SetBlendMode(test.mode)
+		c.SetRGB255(128, 128, 128)
+		c.DrawRectangle(0, 0, 4, 4)
+		c.Fill()
+		if got := c.im.RGBAAt(2, 2); got != test.want {
+			t.Errorf("%v: %v, want %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestBlendModeString(t *testing.T) {
+	if got := BlendColorDodge.String(); got != "color-dodge" {
+		t.Errorf("got %q", got)
+	}
+	if got := BlendMode(99).String(); got != "normal" {
+		t.Errorf("unknown mode is %q, want normal", got)
+	}
+}
@@ -0,0 +1,83 @@
+package drawlib
+
+import "math"
+
+// gaussianBlur approximates a Gaussian blur with standard deviation sigma by
+// three box blurs, in place, on each of the channels interleaved in pix.
+// Samples outside the image count as zero.
+func gaussianBlur(pix []uint8, w, h, stride, channels int, sigma float64) {
+	if sigma <= 0 {
+		return
+	}
+	for _, size := range boxSizes(sigma, 3) {
+		boxBlur(pix, w, h, stride, channels, (size-1)/2)
+	}
+}
+
+// blurReach returns how far gaussianBlur spreads a pixel.
+func blurReach(sigma float64) int {
+	if sigma <= 0 {
+		return 0
+	}
+	reach := 0
+	for _, size := range boxSizes(sigma, 3) {
+		reach += (size - 1) / 2
+	}
+	return reach
+}
+
+// boxBlur blurs each channel with a square box of the given radius.
+func boxBlur(pix []uint8, w, h, stride, channels, radius int) {
+	if radius <= 0 {
+		return
+	}
+	for ch := 0; ch < channels; ch++ {
+		boxBlurLines(pix[ch:], w, h, channels, stride, radius)
+		boxBlurLines(pix[ch:], h, w, stride, channels, radius)
+	}
+}
+
+// boxBlurLines blurs count lines of n samples with a box of radius r.
+// Samples of a line are step bytes apart and lines start lineStep apart.
+func boxBlurLines(pix []uint8, n, count, step, lineStep, r int) {
+	line := make([]int, n)
+	d := 2*r + 1
+	for l := 0; l < count; l++ {
+		base := l * lineStep
+		for i := range line {
+			line[i] = int(pix[base+i*step])
+		}
+		sum := 0
+		for i := 0; i <= r && i < n; i++ {
+			sum += line[i]
+		}
+		for i := range line {
+			pix[base+i*step] = uint8((sum + d/2) / d)
+			if j := i + r + 1; j < n {
+				sum += line[j]
+			}
+			if j := i - r; j >= 0 {
+				sum -= line[j]
+			}
+		}
+	}
+}
+
+// boxSizes returns the odd widths of n box blurs that together approximate
+// a Gaussian blur with standard deviation sigma.
+func boxSizes(sigma float64, n int) []int {
+	v := 12 * sigma * sigma
+	w := int(math.Sqrt(v/float64(n) + 1))
+	if w%2 == 0 {
+		w--
+	}
+	m := int(math.Round((v - float64(n*w*w+4*n*w+3*n)) / float64(-4*w-4)))
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = w
+		if i >= m {
+			sizes[i] += 2
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,231 @@
+package drawlib
+
+import (
+	"math"
+	"sort"
+)
+
+type PathOp int
+
+const (
+	PathOpUnion PathOp = iota
+	PathOpIntersection
+	PathOpDifference
+	PathOpXor
+)
+
+type (
+	boolEdge struct {
+		a, b Vector
+	}
+
+	boolSplit struct {
+		t float64
+		v Vector
+	}
+)
+
+// Union returns the area covered by p or q under the nonzero winding rule.
+func (p *Path) Union(q *Path) *Path {
+	return p.Combine(q, PathOpUnion, FillRuleWinding)
+}
+
+// Intersection returns the area covered by both p and q under the nonzero
+// winding rule.
+func (p *Path) Intersection(q *Path) *Path {
+	return p.Combine(q, PathOpIntersection, FillRuleWinding)
+}
+
+// Difference returns the area covered by p but not by q under the nonzero
+// winding rule.
+func (p *Path) Difference(q *Path) *Path {
+	return p.Combine(q, PathOpDifference, FillRuleWinding)
+}
+
+// Xor returns the area covered by exactly one of p and q under the nonzero
+// winding rule.
+func (p *Path) Xor(q *Path) *Path {
+	return p.Combine(q, PathOpXor, FillRuleWinding)
+}
+
+// Combine flattens p and q, treating every subpath as closed and deciding
+// what is inside each with rule, and returns the polygon outline of op
+// applied to the two areas. The result has no self-intersections, its
+// outer contours and holes wind in opposite directions and it fills the
+// same way under either fill rule.
+func (p *Path) Combine(q *Path, op PathOp, rule FillRule) *Path {
+	a, _ := p.flatten(p.flatteningTolerance())
+	b, _ := q.flatten(q.flatteningTolerance())
+	inside := func(paths [][]*Vector, x, y float64) bool {
+		w := windingNumber(paths, x, y)
+		if rule == FillRuleEvenOdd {
+			return w%2 != 0
+		}
+		return w != 0
+	}
+	in := func(x, y float64) bool {
+		ina, inb := inside(a, x, y), inside(b, x, y)
+		switch op {
+		case PathOpUnion:
+			return ina || inb
+		case PathOpIntersection:
+			return ina && inb
+		case PathOpDifference:
+			return ina && !inb
+		}
+		return ina != inb
+	}
+
+	var kept []boolEdge
+	seen := make(map[boolEdge]bool)
+	for _, e := range splitEdges(append(polygonEdges(a), polygonEdges(b)...)) {
+		dx, dy := e.b.X-e.a.X, e.b.Y-e.a.Y
+		l := math.Hypot(dx, dy)
+		mx, my := (e.a.X+e.b.X)/2, (e.a.Y+e.b.Y)/2
+		d := 1e-7 * (1 + math.Abs(mx) + math.Abs(my))
+		nx, ny := -dy/l*d, dx/l*d
+		left, right := in(mx+nx, my+ny), in(mx-nx, my-ny)
+		if left == right {
+			continue
+		}
+		if !left {
+			e.a, e.b = e.b, e.a
+		}
+		if !seen[e] {
+			seen[e] = true
+			kept = append(kept, e)
+		}
+	}
+	return linkEdges(kept).SetTolerance(p.tolerance)
+}
+
+// polygonEdges returns the edges of the polylines, closing each one.
+func polygonEdges(paths [][]*Vector) []boolEdge {
+	var result []boolEdge
+	for _, path := range paths {
+		for i, a := range path {
+			b := path[(i+1)%len(path)]
+			if a.X != b.X || a.Y != b.Y {
+				result = append(result, boolEdge{*a, *b})
+			}
+		}
+	}
+	return result
+}
+
+// splitEdges splits the edges at every point where they cross or touch one
+// another. The shared points are computed once, so the pieces meet exactly.
+func splitEdges(edges []boolEdge) []boolEdge {
+	const eps = 1e-9
+	splits := make([][]boolSplit, len(edges))
+	on := func(i int, t float64, v Vector) {
+		if t > eps && t < 1-eps {
+			splits[i] = append(splits[i], boolSplit{t, v})
+		}
+	}
+	for i, e := range edges {
+		rx, ry := e.b.X-e.a.X, e.b.Y-e.a.Y
+		for j := i + 1; j < len(edges); j++ {
+			f := edges[j]
+			if math.Max(e.a.X, e.b.X) < math.Min(f.a.X, f.b.X) ||
+				math.Min(e.a.X, e.b.X) > math.Max(f.a.X, f.b.X) ||
+				math.Max(e.a.Y, e.b.Y) < math.Min(f.a.Y, f.b.Y) ||
+				math.Min(e.a.Y, e.b.Y) > math.Max(f.a.Y, f.b.Y) {
+				continue
+			}
+			sx, sy := f.b.X-f.a.X, f.b.Y-f.a.Y
+			qx, qy := f.a.X-e.a.X, f.a.Y-e.a.Y
+			denom := rx*sy - ry*sx
+			if math.Abs(denom) <= eps*math.Hypot(rx, ry)*math.Hypot(sx, sy) {
+				// parallel: collinear edges split each other at their ends
+				if math.Abs(qx*ry-qy*rx) > eps*(rx*rx+ry*ry) {
+					continue
+				}
+				rr, ss := rx*rx+ry*ry, sx*sx+sy*sy
+				on(i, (qx*rx+qy*ry)/rr, f.a)
+				on(i, ((f.b.X-e.a.X)*rx+(f.b.Y-e.a.Y)*ry)/rr, f.b)
+				on(j, (-qx*sx-qy*sy)/ss, e.a)
+				on(j, ((e.b.X-f.a.X)*sx+(e.b.Y-f.a.Y)*sy)/ss, e.b)
+				continue
+			}
+			t := (qx*sy - qy*sx) / denom
+			u := (qx*ry - qy*rx) / denom
+			if t < -eps || t > 1+eps || u < -eps || u > 1+eps {
+				continue
+			}
+			var v Vector
+			switch {
+			case t <= eps:
+				v = e.a
+			case t >= 1-eps:
+				v = e.b
+			case u <= eps:
+				v = f.a
+			case u >= 1-eps:
+				v = f.b
+			default:
+				v = Vector{e.a.X + t*rx, e.a.Y + t*ry}
+			}
+			on(i, t, v)
+			on(j, u, v)
+		}
+	}
+	var result []boolEdge
+	for i, e := range edges {
+		s := splits[i]
+		sort.Slice(s, func(a, b int) bool { return s[a].t < s[b].t })
+		prev := e.a
+		for _, split := range append(s, boolSplit{1, e.b}) {
+			if split.v != prev {
+				result = append(result, boolEdge{prev, split.v})
+				prev = split.v
+			}
+		}
+	}
+	return result
+}
+
+// linkEdges chains directed edges into closed contours. Where several edges
+// leave a point, the one turning furthest towards the inside is taken, so
+// areas that only touch at a point become separate contours.
+func linkEdges(edges []boolEdge) *Path {
+	from := make(map[Vector][]int)
+	for i, e := range edges {
+		from[e.a] = append(from[e.a], i)
+	}
+	used := make([]bool, len(edges))
+	result := NewPath()
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		e := edges[i]
+		result.MoveTo(e.a.X, e.a.Y)
+		for e.b != edges[i].a {
+			next := -1
+			best := -math.Pi
+			dx, dy := e.b.X-e.a.X, e.b.Y-e.a.Y
+			for _, j := range from[e.b] {
+				if used[j] {
+					continue
+				}
+				ex, ey := edges[j].b.X-edges[j].a.X, edges[j].b.Y-edges[j].a.Y
+				if turn := math.Atan2(dx*ey-dy*ex, dx*ex+dy*ey); next < 0 || turn > best {
+					next, best = j, turn
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			result.LineTo(e.b.X, e.b.Y)
+			e = edges[next]
+		}
+		if e.b != edges[i].a {
+			result.LineTo(e.b.X, e.b.Y)
+		}
+		result.ClosePath()
+	}
+	return result
+}
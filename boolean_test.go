@@ -0,0 +1,63 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+// pathArea returns the total signed area of the flattened subpaths of p.
+func pathArea(p *Path) float64 {
+	a := 0.0
+	lines, _ := p.flatten(p.flatteningTolerance())
+	for _, line := range lines {
+		a += polylineArea(line)
+	}
+	return a
+}
+
+func TestPathBooleanOps(t *testing.T) {
+	a := NewPath().DrawRectangle(0, 0, 10, 10)
+	b := NewPath().DrawRectangle(5, 5, 10, 10)
+	for _, test := range []struct {
+		name   string
+		result *Path
+		area   float64
+		bounds [4]float64
+	}{
+		{"union", a.Union(b), 175, [4]float64{0, 0, 15, 15}},
+		{"intersection", a.Intersection(b), 25, [4]float64{5, 5, 10, 10}},
+		{"difference", a.Difference(b), 75, [4]float64{0, 0, 10, 10}},
+		{"xor", a.Xor(b), 150, [4]float64{0, 0, 15, 15}},
+	} {
+		if got := math.Abs(pathArea(test.result)); math.Abs(got-test.area) > 1e-6 {
+			t.Errorf("%s: area %v, want %v", test.name, got, test.area)
+		}
+		if got := pathBounds(test.result); !boundsNear(got, test.bounds) {
+			t.Errorf("%s: bounds %v, want %v", test.name, got, test.bounds)
+		}
+	}
+}
+
+func TestPathCombineDisjoint(t *testing.T) {
+	a := NewPath().DrawRectangle(0, 0, 10, 10)
+	b := NewPath().DrawRectangle(20, 0, 10, 10)
+	if got := a.Intersection(b); !got.IsEmpty() {
+		t.Errorf("disjoint intersection has %d segments", len(got.segments))
+	}
+	if got := math.Abs(pathArea(a.Union(b))); math.Abs(got-200) > 1e-6 {
+		t.Errorf("disjoint union area %v, want 200", got)
+	}
+}
+
+func TestPathCombineFillRule(t *testing.T) {
+	// a ring drawn as two rectangles in the same direction: a hole only
+	// under the even-odd rule
+	ring := NewPath().DrawRectangle(0, 0, 30, 30).DrawRectangle(10, 10, 10, 10)
+	all := NewPath().DrawRectangle(-5, -5, 40, 40)
+	if got := math.Abs(pathArea(ring.Combine(all, PathOpIntersection, FillRuleWinding))); math.Abs(got-900) > 1e-6 {
+		t.Errorf("winding area %v, want 900", got)
+	}
+	if got := math.Abs(pathArea(ring.Combine(all, PathOpIntersection, FillRuleEvenOdd))); math.Abs(got-800) > 1e-6 {
+		t.Errorf("even-odd area %v, want 800", got)
+	}
+}
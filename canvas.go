@@ -11,16 +11,19 @@ import (
 	"unicode"
 
 	"github.com/golang/freetype/raster"
+	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
 )
 
 type LineCap int
 type LineJoin int
 type FillRule int
 type Align int
+type Interpolation int
 
 const (
 	LineCapRound LineCap = iota
@@ -29,6 +32,7 @@ const (
 
 	LineJoinRound LineJoin = iota
 	LineJoinBevel
+	LineJoinMiter
 
 	FillRuleWinding FillRule = iota
 	FillRuleEvenOdd
@@ -36,6 +40,16 @@ const (
 	AlignLeft Align = iota
 	AlignCenter
 	AlignRight
+
+	InterpolationNearest Interpolation = iota
+	InterpolationApproxBiLinear
+	InterpolationBiLinear
+	InterpolationCatmullRom
+)
+
+const (
+	defaultMiterLimit = 10
+	defaultTolerance  = 0.1
 )
 
 var (
@@ -50,25 +64,42 @@ type Canvas struct {
 	rasterizer    *raster.Rasterizer
 	im            *image.RGBA
 	mask          *image.Alpha
+	clips         []clipPath
 	color         color.Color
 	clearSrc      *image.Uniform
 	fillPattern   Pattern
 	strokePattern Pattern
-	strokePath    raster.Path
 	fillPath      raster.Path
+	path          Path
 	start         *Vector
 	current       *Vector
 	hasCurrent    bool
 	hasBegin      bool
 	dashes        []float64
+	dashOffset    float64
 	vertexts      []*Vector
 	lineWidth     float64
 	lineCap       LineCap
 	lineJoin      LineJoin
+	miterLimit    float64
+	tolerance     float64
 	fillRule      FillRule
+	blendMode     BlendMode
+	compositeOp   CompositeOp
+	shadowColor   color.Color
+	shadowX       float64
+	shadowY       float64
+	shadowBlur    float64
+	filters       []Filter
+	interpolation Interpolation
+	sliceEdges    SliceMode
+	sliceCenter   SliceMode
 	fontFace      font.Face
 	fontHeight    float64
+	font          *truetype.Font
+	fontSize      float64
 	matrix        *Matrix
+	recorder      recorder
 }
 
 func NewCanvas(width, height int) *Canvas {
@@ -92,9 +123,14 @@ func NewCanvasForRGBA(im *image.RGBA) *Canvas {
 		fillPattern:   defaultFillStyle,
 		strokePattern: defaultStrokeStyle,
 		lineWidth:     1,
+		miterLimit:    defaultMiterLimit,
+		tolerance:     defaultTolerance,
 		fillRule:      FillRuleWinding,
+		shadowColor:   color.Transparent,
+		interpolation: InterpolationBiLinear,
 		fontFace:      basicfont.Face7x13,
 		fontHeight:    13,
+		fontSize:      13,
 		matrix:        Identity(),
 	}
 }
@@ -124,6 +160,13 @@ func (c *Canvas) SetDash(dashes ...float64) *Canvas {
 	return c
 }
 
+// SetDashOffset sets how far into the dash pattern each subpath starts.
+// Changing it every frame animates the dashes along the path.
+func (c *Canvas) SetDashOffset(offset float64) *Canvas {
+	c.dashOffset = offset
+	return c
+}
+
 func (c *Canvas) SetLineWidth(lineWidth float64) *Canvas {
 	c.lineWidth = lineWidth
 	return c
@@ -164,6 +207,30 @@ func (c *Canvas) SetLineJoinBevel() *Canvas {
 	return c
 }
 
+func (c *Canvas) SetLineJoinMiter() *Canvas {
+	c.lineJoin = LineJoinMiter
+	return c
+}
+
+// SetMiterLimit sets the longest miter allowed, as a multiple of the line
+// width, before a LineJoinMiter join falls back to a bevel. The default is
+// 10.
+func (c *Canvas) SetMiterLimit(limit float64) *Canvas {
+	c.miterLimit = limit
+	return c
+}
+
+// SetTolerance sets how far in device pixels flattened curves, arcs and
+// ellipses may stray from the true shape. Smaller values are smoother and
+// slower. The default is 0.1; values that are not positive and finite are
+// ignored.
+func (c *Canvas) SetTolerance(tolerance float64) *Canvas {
+	if tolerance > 0 && !math.IsInf(tolerance, 1) {
+		c.tolerance = tolerance
+	}
+	return c
+}
+
 func (c *Canvas) SetFillRule(fillRule FillRule) *Canvas {
 	c.fillRule = fillRule
 	return c
@@ -243,8 +310,8 @@ func (c *Canvas) MoveTo(x, y float64) *Canvas {
 	}
 	x, y = c.TransformPoint(x, y)
 	v := NewVector(x, y)
-	c.strokePath.Start(v.Fixed())
 	c.fillPath.Start(v.Fixed())
+	c.path.MoveTo(x, y)
 	c.start = v
 	c.current = v
 	c.hasCurrent = true
@@ -257,8 +324,8 @@ func (c *Canvas) LineTo(x, y float64) *Canvas {
 	} else {
 		x, y = c.TransformPoint(x, y)
 		p := NewVector(x, y)
-		c.strokePath.Add1(p.Fixed())
 		c.fillPath.Add1(p.Fixed())
+		c.path.LineTo(x, y)
 		c.current = p
 	}
 	return c
@@ -302,8 +369,8 @@ func (c *Canvas) QuadraticTo(x1, y1, x2, y2 float64) *Canvas {
 	x2, y2 = c.TransformPoint(x2, y2)
 	v1 := NewVector(x1, y1)
 	v2 := NewVector(x2, y2)
-	c.strokePath.Add2(v1.Fixed(), v2.Fixed())
 	c.fillPath.Add2(v1.Fixed(), v2.Fixed())
+	c.path.QuadraticTo(x1, y1, x2, y2)
 	c.current = v2
 	return c
 }
@@ -317,7 +384,8 @@ func (c *Canvas) CubicTo(x1, y1, x2, y2, x3, y3 float64) *Canvas {
 	x2, y2 = c.TransformPoint(x2, y2)
 	x3, y3 = c.TransformPoint(x3, y3)
 
-	points := CreateCubicBezier(x0, y0, x1, y1, x2, y2, x3, y3)
+	c.path.CubicTo(x1, y1, x2, y2, x3, y3)
+	points := flattenCubic(x0, y0, x1, y1, x2, y2, x3, y3, c.tolerance)
 	previous := c.current.Fixed()
 	for _, p := range points[1:] {
 		f := p.Fixed()
@@ -325,7 +393,6 @@ func (c *Canvas) CubicTo(x1, y1, x2, y2, x3, y3 float64) *Canvas {
 			continue
 		}
 		previous = f
-		c.strokePath.Add1(f)
 		c.fillPath.Add1(f)
 		c.current = p
 	}
@@ -334,16 +401,16 @@ func (c *Canvas) CubicTo(x1, y1, x2, y2, x3, y3 float64) *Canvas {
 
 func (c *Canvas) ClosePath() *Canvas {
 	if c.hasCurrent {
-		c.strokePath.Add1(c.start.Fixed())
 		c.fillPath.Add1(c.start.Fixed())
+		c.path.ClosePath()
 		c.current = c.start
 	}
 	return c
 }
 
 func (c *Canvas) ClearPath() *Canvas {
-	c.strokePath.Clear()
 	c.fillPath.Clear()
+	c.path.Clear()
 	c.hasCurrent = false
 	return c
 }
@@ -352,6 +419,7 @@ func (c *Canvas) NewSubPath() *Canvas {
 	if c.hasCurrent {
 		c.fillPath.Add1(c.start.Fixed())
 	}
+	c.path.NewSubPath()
 	c.hasCurrent = false
 	return c
 }
@@ -369,58 +437,262 @@ func (c *Canvas) capper() raster.Capper {
 }
 
 func (c *Canvas) joiner() raster.Joiner {
-	switch c.lineJoin {
+	return joiner(c.lineJoin, c.miterLimit)
+}
+
+func joiner(lineJoin LineJoin, miterLimit float64) raster.Joiner {
+	switch lineJoin {
 	case LineJoinBevel:
 		return raster.BevelJoiner
 	case LineJoinRound:
 		return raster.RoundJoiner
+	case LineJoinMiter:
+		return miterJoiner(miterLimit)
 	}
 	return nil
 }
 
-func (c *Canvas) stroke(painter raster.Painter) *Canvas {
-	path := c.strokePath
+// miterJoiner returns a joiner that extends the outer edges of a join to
+// meet at a point, falling back to a bevel when the miter would be longer
+// than limit times the line width.
+func miterJoiner(limit float64) raster.Joiner {
+	return raster.JoinerFunc(func(lhs, rhs raster.Adder, halfWidth fixed.Int26_6, pivot, n0, n1 fixed.Point26_6) {
+		bx, by := float64(n0.X+n1.X), float64(n0.Y+n1.Y)
+		b2 := bx*bx + by*by
+		h := float64(halfWidth)
+		// the miter is 2h/|n0+n1| line widths long
+		if b2 == 0 || 4*h*h > limit*limit*b2 {
+			raster.BevelJoiner.Join(lhs, rhs, halfWidth, pivot, n0, n1)
+			return
+		}
+		k := 2 * h * h / b2
+		tip := fixed.Point26_6{X: fixed.Int26_6(bx * k), Y: fixed.Int26_6(by * k)}
+		// the outer side is chosen as in raster.RoundJoiner
+		if float64(n0.X)*float64(n1.Y)-float64(n0.Y)*float64(n1.X) >= 0 {
+			lhs.Add1(pivot.Add(tip))
+			lhs.Add1(pivot.Add(n1))
+			rhs.Add1(pivot.Sub(n1))
+		} else {
+			lhs.Add1(pivot.Add(n1))
+			rhs.Add1(pivot.Sub(tip))
+			rhs.Add1(pivot.Sub(n1))
+		}
+	})
+}
+
+// dashedStrokePath returns the flattened and dashed path that stroke hands
+// to the rasterizer. Closed loops come back separately: they run on over
+// their first segment so that the start is joined, and must be stroked with
+// butt caps.
+func (c *Canvas) dashedStrokePath() (open, closed raster.Path) {
+	// stroke the path as the recorders see it, so that replays match
+	paths, isClosed := pathFromFixed(c.path.fixedPath()).flatten(c.tolerance)
 	if len(c.dashes) > 0 {
-		path = rasterPath(dashPath(flattenPath(path), c.dashes))
-	} else {
-		path = rasterPath(flattenPath(path))
+		paths, isClosed = dashPath(paths, isClosed, c.dashes, c.dashOffset)
+	}
+	var lines, loops [][]*Vector
+	for i, path := range paths {
+		if !isClosed[i] {
+			lines = append(lines, path)
+			continue
+		}
+		// run on to the first point that survives rasterPath, so the start
+		// gets a join
+		j := 1
+		for j < len(path)-1 && path[j].Fixed() == path[0].Fixed() {
+			j++
+		}
+		loops = append(loops, append(path, path[j]))
 	}
+	return rasterPath(lines), rasterPath(loops)
+}
+
+func (c *Canvas) stroke(painter raster.Painter) *Canvas {
+	open, closed := c.dashedStrokePath()
 	r := c.rasterizer
 	r.UseNonZeroWinding = true
 	r.Clear()
-	r.AddStroke(path, Fix(c.lineWidth), c.capper(), c.joiner())
+	r.AddStroke(open, Fix(c.lineWidth), c.capper(), c.joiner())
+	r.AddStroke(closed, Fix(c.lineWidth), raster.ButtCapper, c.joiner())
 	r.Rasterize(painter)
 	return c
 }
 
-func (c *Canvas) fill(painter raster.Painter) *Canvas {
+func (c *Canvas) closedFillPath() raster.Path {
 	path := c.fillPath
 	if c.hasCurrent {
 		path = make(raster.Path, len(c.fillPath))
 		copy(path, c.fillPath)
 		path.Add1(c.start.Fixed())
 	}
+	return path
+}
+
+// painter returns a painter for pattern that honors the clip mask, the
+// blend mode and the operator, using the faster RGBA painter when it can.
+func (c *Canvas) painter(pattern Pattern) raster.Painter {
+	if p, ok := pattern.(*solidPattern); ok && c.mask == nil && c.sourceOver() {
+		painter := raster.NewRGBAPainter(c.im)
+		painter.SetColor(p.color)
+		return painter
+	}
+	return newPatternPainter(c.im, c.mask, c.devicePattern(pattern), c.blendMode, c.compositeOp)
+}
+
+// drawShape draws a fill, stroke, text or image. render draws the shape
+// onto a transparent layer and direct draws it onto the canvas and records
+// it. With filters set the filtered layer is drawn and recorded instead.
+// bounds holds the shape in device space and limits the layers.
+func (c *Canvas) drawShape(bounds image.Rectangle, render func(layer *image.RGBA), direct func()) {
+	if len(c.filters) == 0 {
+		c.drawShadow(bounds, render)
+		direct()
+		return
+	}
+	b := c.im.Bounds()
+	if reach, ok := filtersReach(c.filters); ok {
+		b = bounds.Inset(-reach).Intersect(b)
+	}
+	if b.Empty() {
+		return
+	}
+	layer := image.NewRGBA(b)
+	render(layer)
+	for _, f := range c.filters {
+		f.Apply(layer)
+	}
+	c.drawShadow(b, func(dst *image.RGBA) {
+		draw.Draw(dst, dst.Bounds(), layer, dst.Bounds().Min, draw.Src)
+	})
+	c.compositeLayer(layer)
+	c.recordLayer(layer)
+}
+
+// drawPieces draws what pieces draws, such as the parts of a nine-slice, as
+// a single shape: the pieces cast one shadow and are filtered together.
+// bounds holds them in device space.
+func (c *Canvas) drawPieces(bounds image.Rectangle, pieces func()) {
+	if !c.hasShadow() && len(c.filters) == 0 {
+		pieces()
+		return
+	}
+	shadow, filters := c.shadowColor, c.filters
+	render := func(layer *image.RGBA) {
+		im, rec, mask, mode, op := c.im, c.recorder, c.mask, c.blendMode, c.compositeOp
+		c.im, c.recorder, c.mask = layer, nil, nil
+		c.blendMode, c.compositeOp = BlendNormal, CompositeSourceOver
+		c.shadowColor, c.filters = color.Transparent, nil
+		pieces()
+		c.im, c.recorder, c.mask, c.blendMode, c.compositeOp = im, rec, mask, mode, op
+		c.shadowColor, c.filters = shadow, filters
+	}
+	c.drawShape(bounds, render, func() {
+		c.shadowColor = color.Transparent
+		pieces()
+		c.shadowColor = shadow
+	})
+}
+
+// drawLayer draws a shape through a layer limited to bounds, as needed by
+// the blend modes and operators the painters do not handle.
+func (c *Canvas) drawLayer(bounds image.Rectangle, render func(layer *image.RGBA)) {
+	layer := image.NewRGBA(bounds.Intersect(c.im.Bounds()))
+	render(layer)
+	c.compositeLayer(layer)
+}
+
+// recordLayer records layer, positioned on the canvas by its bounds, as an
+// image.
+func (c *Canvas) recordLayer(layer *image.RGBA) {
+	if c.recorder == nil {
+		return
+	}
+	b := layer.Bounds()
+	im := &image.RGBA{Pix: layer.Pix, Stride: layer.Stride, Rect: b.Sub(b.Min)}
+	c.recorder.image(c, im, Translate(float64(b.Min.X), float64(b.Min.Y)))
+}
+
+// paintedBounds returns the bounds of the pixels of layer that are not
+// transparent.
+func paintedBounds(layer *image.RGBA) image.Rectangle {
+	var b image.Rectangle
+	r := layer.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if layer.Pix[layer.PixOffset(x, y)+3] != 0 {
+				b = b.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return b
+}
+
+// pixelBounds returns the pixels touched by the device space box x0, y0,
+// x1, y1, with a pixel to spare for antialiasing and resampling.
+func pixelBounds(x0, y0, x1, y1 float64) image.Rectangle {
+	return image.Rect(int(math.Floor(x0))-1, int(math.Floor(y0))-1, int(math.Ceil(x1))+1, int(math.Ceil(y1))+1)
+}
+
+// transformedBounds returns the pixels touched by the box x0, y0, x1, y1
+// transformed by m.
+func transformedBounds(m *Matrix, x0, y0, x1, y1 float64) image.Rectangle {
+	bx0, by0 := math.Inf(1), math.Inf(1)
+	bx1, by1 := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}} {
+		x, y := m.TransformPoint(p[0], p[1])
+		bx0, by0 = math.Min(bx0, x), math.Min(by0, y)
+		bx1, by1 = math.Max(bx1, x), math.Max(by1, y)
+	}
+	return pixelBounds(bx0, by0, bx1, by1)
+}
+
+// rasterBounds returns the pixels touched by the raster path p, whose
+// control points enclose its curves.
+func rasterBounds(p raster.Path) image.Rectangle {
+	if len(p) == 0 {
+		return image.Rectangle{}
+	}
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	walkPath(p, func(op int, points []*Vector) {
+		for _, v := range points {
+			x0, y0 = math.Min(x0, v.X), math.Min(y0, v.Y)
+			x1, y1 = math.Max(x1, v.X), math.Max(y1, v.Y)
+		}
+	})
+	return pixelBounds(x0, y0, x1, y1)
+}
+
+// strokeBounds returns the pixels the current stroke may touch, allowing
+// for miters and square caps.
+func (c *Canvas) strokeBounds() image.Rectangle {
+	if c.path.IsEmpty() {
+		return image.Rectangle{}
+	}
+	x0, y0, x1, y1 := c.path.Bounds()
+	pad := c.lineWidth / 2 * math.Max(math.Sqrt2, c.miterLimit)
+	return pixelBounds(x0-pad, y0-pad, x1+pad, y1+pad)
+}
+
+func (c *Canvas) fill(painter raster.Painter) *Canvas {
 	r := c.rasterizer
 	r.UseNonZeroWinding = c.fillRule == FillRuleWinding
 	r.Clear()
-	r.AddPath(path)
+	r.AddPath(c.closedFillPath())
 	r.Rasterize(painter)
 	return c
 }
 
 func (c *Canvas) StrokePreserve() *Canvas {
-	var painter raster.Painter
-	if c.mask == nil {
-		if pattern, ok := c.strokePattern.(*solidPattern); ok {
-			p := raster.NewRGBAPainter(c.im)
-			p.SetColor(pattern.color)
-			painter = p
+	c.drawShape(c.strokeBounds(), func(layer *image.RGBA) {
+		c.stroke(newPatternPainter(layer, nil, c.devicePattern(c.strokePattern), BlendNormal, CompositeSourceOver))
+	}, func() {
+		c.stroke(c.painter(c.strokePattern))
+		if c.recorder != nil {
+			path, closed := c.path.fixedPath()
+			c.recorder.stroke(c, path, closed, c.strokePattern)
 		}
-	}
-	if painter == nil {
-		painter = newPatternPainter(c.im, c.mask, c.strokePattern)
-	}
-	c.stroke(painter)
+	})
 	return c
 }
 
@@ -460,18 +732,14 @@ func (c *Canvas) StrokeRGBA255(r, g, b, a int) {
 }
 
 func (c *Canvas) FillPreserve() *Canvas {
-	var painter raster.Painter
-	if c.mask == nil {
-		if pattern, ok := c.fillPattern.(*solidPattern); ok {
-			p := raster.NewRGBAPainter(c.im)
-			p.SetColor(pattern.color)
-			painter = p
+	c.drawShape(rasterBounds(c.closedFillPath()), func(layer *image.RGBA) {
+		c.fill(newPatternPainter(layer, nil, c.devicePattern(c.fillPattern), BlendNormal, CompositeSourceOver))
+	}, func() {
+		c.fill(c.painter(c.fillPattern))
+		if c.recorder != nil {
+			c.recorder.fill(c, c.closedFillPath(), c.fillPattern)
 		}
-	}
-	if painter == nil {
-		painter = newPatternPainter(c.im, c.mask, c.fillPattern)
-	}
-	c.fill(painter)
+	})
 	return c
 }
 
@@ -521,6 +789,11 @@ func (c *Canvas) ClipPreserve() *Canvas {
 		draw.DrawMask(mask, mask.Bounds(), clip, image.ZP, c.mask, image.ZP, draw.Over)
 		c.mask = mask
 	}
+	if c.recorder != nil {
+		path := append(raster.Path(nil), c.closedFillPath()...)
+		c.clips = append(c.clips[:len(c.clips):len(c.clips)], clipPath{path, c.fillRule})
+		c.recorder.clip(c, path)
+	}
 	return c
 }
 
@@ -557,9 +830,53 @@ func (c *Canvas) Clip() *Canvas {
 
 func (c *Canvas) ResetClip() *Canvas {
 	c.mask = nil
+	c.clips = nil
+	if c.recorder != nil {
+		c.recorder.resetClip(c)
+	}
 	return c
 }
 
+type (
+	// clipPath is a recorded clip, kept so that recorders can be brought
+	// back to an earlier clip.
+	clipPath struct {
+		path raster.Path
+		rule FillRule
+	}
+
+	savedClip struct {
+		mask  *image.Alpha
+		clips []clipPath
+	}
+)
+
+func (c *Canvas) saveClip() savedClip {
+	return savedClip{c.mask, c.clips}
+}
+
+// restoreClip returns to a clip from saveClip. Recorders cannot drop single
+// clips, so when the clips changed they are reset and the saved ones
+// recorded again.
+func (c *Canvas) restoreClip(s savedClip) {
+	c.mask = s.mask
+	n := len(s.clips)
+	if len(c.clips) == n && (n == 0 || &c.clips[n-1] == &s.clips[n-1]) {
+		return
+	}
+	c.clips = s.clips
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.resetClip(c)
+	rule := c.fillRule
+	for _, clip := range s.clips {
+		c.fillRule = clip.rule
+		c.recorder.clip(c, clip.path)
+	}
+	c.fillRule = rule
+}
+
 // arg 1 for gray color,
 // arg 3 for rgb color,
 // arg 4 for rgba color
@@ -597,11 +914,23 @@ func (c *Canvas) Background(i ...interface{}) *Canvas {
 
 func (c *Canvas) Clear() *Canvas {
 	draw.Draw(c.im, c.im.Bounds(), c.clearSrc, image.ZP, draw.Src)
+	if c.recorder != nil {
+		c.recorder.clear(c)
+	}
 	return c
 }
 
 func (c *Canvas) SetPixel(x, y int) *Canvas {
 	c.im.Set(x, y, c.color)
+	if c.recorder != nil {
+		var path raster.Path
+		path.Start(fixed.P(x, y))
+		path.Add1(fixed.P(x+1, y))
+		path.Add1(fixed.P(x+1, y+1))
+		path.Add1(fixed.P(x, y+1))
+		path.Add1(fixed.P(x, y))
+		c.recorder.fill(c, path, NewSolidPattern(c.color))
+	}
 	return c
 }
 
@@ -615,96 +944,42 @@ func (c *Canvas) DrawPoint(x, y, r float64) *Canvas {
 }
 
 func (c *Canvas) DrawLine(x1, y1, x2, y2 float64) *Canvas {
-	c.MoveTo(x1, y1)
-	c.LineTo(x2, y2)
+	drawLine(c, x1, y1, x2, y2)
 	return c
 }
 
 func (c *Canvas) DrawRectangle(x, y, w, h float64) *Canvas {
-	c.NewSubPath()
-	c.MoveTo(x, y)
-	c.LineTo(x+w, y)
-	c.LineTo(x+w, y+h)
-	c.LineTo(x, y+h)
-	c.ClosePath()
+	drawRectangle(c, x, y, w, h)
 	return c
 }
 
 func (c *Canvas) DrawRoundedRectangle(x, y, w, h, r float64) *Canvas {
-	x0, x1, x2, x3 := x, x+r, x+w-r, x+w
-	y0, y1, y2, y3 := y, y+r, y+h-r, y+h
-	c.NewSubPath()
-	c.MoveTo(x1, y0)
-	c.LineTo(x2, y0)
-	c.DrawArc(x2, y1, r, ToRadians(270), ToRadians(360))
-	c.LineTo(x3, y2)
-	c.DrawArc(x2, y2, r, ToRadians(0), ToRadians(90))
-	c.LineTo(x1, y3)
-	c.DrawArc(x1, y2, r, ToRadians(90), ToRadians(180))
-	c.LineTo(x0, y1)
-	c.DrawArc(x1, y1, r, ToRadians(180), ToRadians(270))
-	c.ClosePath()
+	drawRoundedRectangle(c, x, y, w, h, r)
 	return c
 }
 
 func (c *Canvas) DrawEllipticalArc(x, y, rx, ry, angle1, angle2 float64) *Canvas {
-	const n = 16
-	for i := 0; i < n; i++ {
-		p1 := float64(i+0) / n
-		p2 := float64(i+1) / n
-		a1 := angle1 + (angle2-angle1)*p1
-		a2 := angle1 + (angle2-angle1)*p2
-		x0 := x + rx*math.Cos(a1)
-		y0 := y + ry*math.Sin(a1)
-		x1 := x + rx*math.Cos(a1+(a2-a1)/2)
-		y1 := y + ry*math.Sin(a1+(a2-a1)/2)
-		x2 := x + rx*math.Cos(a2)
-		y2 := y + ry*math.Sin(a2)
-		cx := 2*x1 - x0/2 - x2/2
-		cy := 2*y1 - y0/2 - y2/2
-		if i == 0 {
-			if c.hasCurrent {
-				c.LineTo(x0, y0)
-			} else {
-				c.MoveTo(x0, y0)
-			}
-		}
-		c.QuadraticTo(cx, cy, x2, y2)
-	}
+	drawEllipticalArc(c, x, y, rx, ry, angle1, angle2)
 	return c
 }
 
 func (c *Canvas) DrawEllipse(x, y, rx, ry float64) *Canvas {
-	c.NewSubPath()
-	c.DrawEllipticalArc(x, y, rx, ry, 0, 2*math.Pi)
-	c.ClosePath()
+	drawEllipse(c, x, y, rx, ry)
 	return c
 }
 
 func (c *Canvas) DrawArc(x, y, r, angle1, angle2 float64) *Canvas {
-	c.DrawEllipticalArc(x, y, r, r, angle1, angle2)
+	drawEllipticalArc(c, x, y, r, r, angle1, angle2)
 	return c
 }
 
 func (c *Canvas) DrawCircle(x, y, r float64) *Canvas {
-	c.NewSubPath()
-	c.DrawEllipticalArc(x, y, r, r, 0, 2*math.Pi)
-	c.ClosePath()
+	drawEllipse(c, x, y, r, r)
 	return c
 }
 
 func (c *Canvas) DrawRegularPolygon(n int, x, y, r, rotation float64) *Canvas {
-	angle := 2 * math.Pi / float64(n)
-	rotation -= math.Pi / 2
-	if n%2 == 0 {
-		rotation += angle / 2
-	}
-	c.NewSubPath()
-	for i := 0; i < n; i++ {
-		a := rotation + angle*float64(i)
-		c.LineTo(x+r*math.Cos(a), y+r*math.Sin(a))
-	}
-	c.ClosePath()
+	drawRegularPolygon(c, n, x, y, r, rotation)
 	return c
 }
 
@@ -717,32 +992,90 @@ func (c *Canvas) DrawImageAnchored(im image.Image, x, y int, ax, ay float64) *Ca
 	s := im.Bounds().Size()
 	x -= int(ax * float64(s.X))
 	y -= int(ay * float64(s.Y))
-	transformer := draw.BiLinear
-	fx, fy := float64(x), float64(y)
-	m := c.matrix.Translate(fx, fy)
-	s2d := f64.Aff3{m.XX, m.XY, m.X0, m.YX, m.YY, m.Y0}
-	if c.mask == nil {
-		transformer.Transform(c.im, s2d, im, im.Bounds(), draw.Over, nil)
-	} else {
-		transformer.Transform(c.im, s2d, im, im.Bounds(), draw.Over, &draw.Options{
-			DstMask:  c.mask,
-			DstMaskP: image.ZP,
-		})
+	c.drawImage(im, im.Bounds(), c.matrix.Translate(float64(x), float64(y)))
+	return c
+}
+
+// DrawImageRect draws the part src of im scaled into the rectangle at x, y
+// with width w and height h. Any part of src outside im is transparent, so
+// the rest keeps its place and scale.
+func (c *Canvas) DrawImageRect(im image.Image, src image.Rectangle, x, y, w, h float64) *Canvas {
+	part := src.Intersect(im.Bounds())
+	if part.Empty() {
+		return c
 	}
+	sx, sy := w/float64(src.Dx()), h/float64(src.Dy())
+	m := c.matrix.Translate(x, y).Scale(sx, sy).Translate(-float64(src.Min.X), -float64(src.Min.Y))
+	c.drawImage(im, part, m)
+	return c
+}
+
+// SetInterpolation sets how images are resampled when they are drawn
+// transformed. The default is InterpolationBiLinear.
+func (c *Canvas) SetInterpolation(interpolation Interpolation) *Canvas {
+	c.interpolation = interpolation
 	return c
 }
 
+func (c *Canvas) interpolator() draw.Interpolator {
+	switch c.interpolation {
+	case InterpolationNearest:
+		return draw.NearestNeighbor
+	case InterpolationApproxBiLinear:
+		return draw.ApproxBiLinear
+	case InterpolationCatmullRom:
+		return draw.CatmullRom
+	}
+	return draw.BiLinear
+}
+
+// drawImage draws the part sr of im with m mapping it to device space.
+func (c *Canvas) drawImage(im image.Image, sr image.Rectangle, m *Matrix) {
+	transformer := c.interpolator()
+	s2d := f64.Aff3{m.XX, m.XY, m.X0, m.YX, m.YY, m.Y0}
+	render := func(layer *image.RGBA) {
+		transformer.Transform(layer, s2d, im, sr, draw.Over, nil)
+	}
+	bounds := transformedBounds(m, float64(sr.Min.X), float64(sr.Min.Y), float64(sr.Max.X), float64(sr.Max.Y))
+	c.drawShape(bounds, render, func() {
+		if !c.sourceOver() {
+			c.drawLayer(bounds, render)
+		} else if c.mask == nil {
+			render(c.im)
+		} else {
+			transformer.Transform(c.im, s2d, im, sr, draw.Over, &draw.Options{
+				DstMask:  c.mask,
+				DstMaskP: image.ZP,
+			})
+		}
+		if c.recorder != nil {
+			if sr != im.Bounds() {
+				part := image.NewRGBA(sr)
+				draw.Draw(part, sr, im, sr.Min, draw.Src)
+				im = part
+			}
+			c.recorder.image(c, im, m)
+		}
+	})
+}
+
 func (c *Canvas) SetFontFace(fontFace font.Face) *Canvas {
 	c.fontFace = fontFace
 	c.fontHeight = float64(fontFace.Metrics().Height) / 64
+	c.font = nil
+	c.fontSize = c.fontHeight
 	return c
 }
 
 func (c *Canvas) LoadFontFace(path string, points float64) error {
-	face, err := LoadFontFace(path, points)
+	f, err := LoadFont(path)
 	if err == nil {
-		c.fontFace = face
+		c.fontFace = truetype.NewFace(f, &truetype.Options{
+			Size: points,
+		})
 		c.fontHeight = points * 72 / 96
+		c.font = f
+		c.fontSize = points
 	}
 	return err
 }
@@ -781,6 +1114,25 @@ func (c *Canvas) drawString(im *image.RGBA, s string, x, y float64) {
 	}
 }
 
+// textBounds returns the pixels s drawn at x, y may touch, leaving room
+// for glyphs overhanging their advance.
+func (c *Canvas) textBounds(s string, x, y float64) image.Rectangle {
+	w, _ := c.MeasureString(s)
+	metrics := c.fontFace.Metrics()
+	pad := c.fontHeight / 2
+	return transformedBounds(c.matrix, x-pad, y-Unfix(metrics.Ascent)-pad, x+w+pad, y+Unfix(metrics.Descent)+pad)
+}
+
+// textImage renders s drawn at x, y into an image cropped to the text, for
+// recording faces without outlines, and returns the matrix placing it on
+// the canvas. The image is empty when the text is off the canvas.
+func (c *Canvas) textImage(s string, x, y float64) (*image.RGBA, *Matrix) {
+	b := c.textBounds(s, x, y).Intersect(c.im.Bounds())
+	im := image.NewRGBA(b)
+	c.drawString(im, s, x, y)
+	return &image.RGBA{Pix: im.Pix, Stride: im.Stride, Rect: b.Sub(b.Min)}, Translate(float64(b.Min.X), float64(b.Min.Y))
+}
+
 func (c *Canvas) DrawString(s string, x, y float64) *Canvas {
 	c.DrawStringAnchored(s, x, y, 0, 0)
 	return c
@@ -790,13 +1142,20 @@ func (c *Canvas) DrawStringAnchored(s string, x, y, ax, ay float64) *Canvas {
 	w, h := c.MeasureString(s)
 	x -= ax * w
 	y += ay * h
-	if c.mask == nil {
-		c.drawString(c.im, s, x, y)
-	} else {
-		im := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
-		c.drawString(im, s, x, y)
-		draw.DrawMask(c.im, c.im.Bounds(), im, image.ZP, c.mask, image.ZP, draw.Over)
+	render := func(layer *image.RGBA) {
+		c.drawString(layer, s, x, y)
 	}
+	bounds := c.textBounds(s, x, y)
+	c.drawShape(bounds, render, func() {
+		if c.mask == nil && c.sourceOver() {
+			render(c.im)
+		} else {
+			c.drawLayer(bounds, render)
+		}
+		if c.recorder != nil {
+			c.recorder.text(c, s, x, y)
+		}
+	})
 	return c
 }
 
@@ -967,10 +1326,50 @@ func (c *Canvas) Pop() *Canvas {
 	x, s := s[len(s)-1], s[:len(s)-1]
 	*c = *x
 	c.mask = before.mask
-	c.strokePath = before.strokePath
+	c.clips = before.clips
 	c.fillPath = before.fillPath
+	c.path = before.path
 	c.start = before.start
 	c.current = before.current
 	c.hasCurrent = before.hasCurrent
 	return c
 }
+
+// PushGroup saves the state like Push and redirects drawing to a new
+// transparent layer the size of the canvas. The matrix and clip carry over,
+// and clips set inside the group end with it. Groups are rasterized: while
+// one is open nothing is recorded, and PopGroup records the painted part of
+// the layer as an image, so SVG, PDF and display list output holds the
+// group as pixels rather than as vector operations.
+func (c *Canvas) PushGroup() *Canvas {
+	c.Push()
+	c.im = image.NewRGBA(c.im.Bounds())
+	c.recorder = nil
+	return c
+}
+
+// PopGroup restores the state and clip saved by the matching PushGroup and
+// draws the group's layer onto the canvas with opacity, clamped to 0..1,
+// and mode.
+func (c *Canvas) PopGroup(opacity float64, mode BlendMode) *Canvas {
+	layer := c.im
+	saved := c.stack[len(c.stack)-1]
+	c.Pop()
+	c.restoreClip(savedClip{saved.mask, saved.clips})
+	b := paintedBounds(layer)
+	if !(opacity > 0) || b.Empty() {
+		return c
+	}
+	layer = layer.SubImage(b).(*image.RGBA)
+	if opacity < 1 {
+		for i := range layer.Pix {
+			layer.Pix[i] = uint8(float64(layer.Pix[i])*opacity + 0.5)
+		}
+	}
+	mask, blendMode, op := c.mask, c.blendMode, c.compositeOp
+	c.mask, c.blendMode, c.compositeOp = nil, mode, CompositeSourceOver
+	c.compositeLayer(layer)
+	c.recordLayer(layer)
+	c.mask, c.blendMode, c.compositeOp = mask, blendMode, op
+	return c
+}
@@ -0,0 +1,196 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// strokeSquare strokes a 20x20 square at 20,20 with wide mitered butt
+// lines, ending it with a close or with a line back to the start.
+func strokeSquare(c *Canvas, close bool) {
+	c.SetLineWidth(10)
+	c.SetLineCap(LineCapButt)
+	c.SetLineJoin(LineJoinMiter)
+	c.MoveTo(20, 20)
+	c.LineTo(40, 20)
+	c.LineTo(40, 40)
+	c.LineTo(20, 40)
+	if close {
+		c.ClosePath()
+	} else {
+		c.LineTo(20, 20)
+	}
+}
+
+func TestStrokeClosedFlag(t *testing.T) {
+	for _, close := range []bool{true, false} {
+		c := NewCanvas(60, 60)
+		strokeSquare(c, close)
+		c.SetRGB(0, 0, 0)
+		c.Stroke()
+		// the outer corner at the start is only mitered when closed
+		if got := c.im.RGBAAt(16, 16).A == 255; got != close {
+			t.Errorf("closed %v: start corner painted %v", close, got)
+		}
+		if c.im.RGBAAt(44, 44).A != 255 {
+			t.Errorf("closed %v: far corner not painted", close)
+		}
+	}
+}
+
+func TestDashedClosedPath(t *testing.T) {
+	c := NewCanvas(60, 60)
+	strokeSquare(c, true)
+	// the first dash covers the whole square, so it stays closed
+	c.SetDash(100, 10)
+	if open, closed := c.dashedStrokePath(); len(open) != 0 || len(closed) == 0 {
+		t.Errorf("unbroken dash: %d open and %d closed path values, want only closed", len(open), len(closed))
+	}
+
+	// a dash running through the start is kept in one piece
+	c.SetDash(25, 10)
+	lines, closed := c.path.flatten(c.tolerance)
+	dashes, dashClosed := dashPath(lines, closed, c.dashes, c.dashOffset)
+	if len(dashes) != 2 || dashClosed[0] || dashClosed[1] {
+		t.Fatalf("got %d dashes closed %v, want 2 open", len(dashes), dashClosed)
+	}
+	if wrapped := dashes[len(dashes)-1]; *wrapped[len(wrapped)-1] != (Vector{40, 25}) {
+		t.Errorf("wrapped dash ends at %v, want 40,25", *wrapped[len(wrapped)-1])
+	}
+
+	// a polyline that merely returns to its start is not wrapped
+	c.ClearPath()
+	strokeSquare(c, false)
+	lines, closed = c.path.flatten(c.tolerance)
+	if dashes, _ := dashPath(lines, closed, c.dashes, c.dashOffset); len(dashes) != 3 {
+		t.Errorf("open polyline: got %d dashes, want 3", len(dashes))
+	}
+}
+
+func TestDisplayListKeepsClose(t *testing.T) {
+	dl := RecordDisplayList(60, 60, func(c *Canvas) {
+		strokeSquare(c, true)
+		c.Stroke()
+		strokeSquare(c, false)
+		c.Stroke()
+	})
+	js, err := json.Marshal(dl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(js, []byte(`"closed":[true]`)); n != 1 {
+		t.Errorf("%d closed strokes recorded, want 1:\n%s", n, js)
+	}
+	var decoded DisplayList
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	want := NewCanvas(60, 60)
+	strokeSquare(want, true)
+	want.Stroke()
+	strokeSquare(want, false)
+	want.Stroke()
+	got := NewCanvas(60, 60)
+	got.DrawDisplayList(&decoded)
+	if !bytes.Equal(got.im.Pix, want.im.Pix) {
+		t.Error("replayed strokes differ")
+	}
+}
+
+func TestPopGroupOpacity(t *testing.T) {
+	for _, test := range []struct {
+		opacity float64
+		want    color.RGBA
+	}{
+		{1, color.RGBA{255, 0, 0, 255}},
+		{2, color.RGBA{255, 0, 0, 255}},
+		{0.5, color.RGBA{128, 0, 127, 255}},
+		{0, color.RGBA{0, 0, 255, 255}},
+		{-1, color.RGBA{0, 0, 255, 255}},
+	} {
+		c := NewCanvas(4, 4)
+		c.Background(0, 0, 255)
+		c.PushGroup()
+		c.SetRGB(1, 0, 0)
+		c.DrawRectangle(0, 0, 4, 4)
+		c.Fill()
+		c.PopGroup(test.opacity, BlendNormal)
+		if got := c.im.RGBAAt(1, 1); got != test.want {
+			t.Errorf("opacity %v: %v, want %v", test.opacity, got, test.want)
+		}
+	}
+}
+
+func TestPopGroupRestoresClip(t *testing.T) {
+	c := NewCanvas(20, 20)
+	c.DrawRectangle(0, 0, 15, 20)
+	c.Clip()
+	c.PushGroup()
+	c.DrawRectangle(0, 0, 10, 20)
+	c.Clip()
+	c.PopGroup(1, BlendNormal)
+	c.SetRGB(1, 0, 0)
+	c.DrawRectangle(0, 0, 20, 20)
+	c.Fill()
+	for _, test := range []struct {
+		x    int
+		want uint8
+	}{
+		{5, 255},
+		{12, 255},
+		{17, 0},
+	} {
+		if got := c.im.RGBAAt(test.x, 5).A; got != test.want {
+			t.Errorf("pixel %d alpha %d, want %d", test.x, got, test.want)
+		}
+	}
+}
+
+func TestGroupRecordedAsImage(t *testing.T) {
+	c := NewSVGCanvas(40, 40)
+	c.PushGroup()
+	c.DrawRectangle(0, 0, 20, 40)
+	c.Clip()
+	c.SetRGB(1, 0, 0)
+	c.DrawRectangle(10, 10, 10, 10)
+	c.Fill()
+	c.PopGroup(0.5, BlendNormal)
+	c.SetRGB(0, 0, 1)
+	c.DrawRectangle(30, 30, 5, 5)
+	c.Fill()
+	var b bytes.Buffer
+	if err := c.EncodeSVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if n := strings.Count(out, "<image "); n != 1 {
+		t.Fatalf("%d images recorded, want the group as one:\n%s", n, out)
+	}
+	if !strings.Contains(out, `transform="matrix(1 0 0 1 10 10)" x="0" y="0" width="10" height="10"`) {
+		t.Errorf("group image is not cropped to the painted pixels:\n%s", out)
+	}
+	if n := strings.Count(out, "<path "); n != 1 || strings.Contains(out, "clip-path") {
+		t.Errorf("want only the fill after the group as a path, unclipped:\n%s", out)
+	}
+}
+
+func TestDrawImageRectOutsideSource(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range im.Pix {
+		im.Pix[i] = 255
+	}
+	c := NewCanvas(40, 40)
+	c.SetInterpolation(InterpolationNearest)
+	// the right half of the source lies outside the image
+	c.DrawImageRect(im, image.Rect(0, 0, 20, 10), 0, 0, 40, 20)
+	if got := c.im.RGBAAt(10, 10); got.A != 255 {
+		t.Errorf("inside the source: %v, want white", got)
+	}
+	if got := c.im.RGBAAt(30, 10); got.A != 0 {
+		t.Errorf("outside the source: %v, want transparent", got)
+	}
+}
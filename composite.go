@@ -0,0 +1,177 @@
+package drawlib
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+type CompositeOp int
+
+const (
+	CompositeSourceOver CompositeOp = iota
+	CompositeClear
+	CompositeCopy
+	CompositeSourceIn
+	CompositeSourceOut
+	CompositeSourceAtop
+	CompositeDestinationOver
+	CompositeDestinationIn
+	CompositeDestinationOut
+	CompositeDestinationAtop
+	CompositeXor
+	CompositeLighter
+)
+
+var compositeOpNames = [...]string{
+	"source-over", "clear", "copy", "source-in", "source-out", "source-atop",
+	"destination-over", "destination-in", "destination-out",
+	"destination-atop", "xor", "lighter",
+}
+
+// String returns the name of the operator as used by the HTML canvas
+// globalCompositeOperation.
+func (op CompositeOp) String() string {
+	if op < 0 || int(op) >= len(compositeOpNames) {
+		return "source-over"
+	}
+	return compositeOpNames[op]
+}
+
+// SetCompositeOp sets the Porter-Duff operator used to combine fills,
+// strokes, text and images with the canvas. As with HTML canvas, copy,
+// source-in, source-out, destination-in and destination-atop also affect
+// the canvas outside the shape, within the clip. CompositeClear erases the
+// canvas under the shape. SVG and PDF output ignore the operator.
+func (c *Canvas) SetCompositeOp(op CompositeOp) *Canvas {
+	c.compositeOp = op
+	return c
+}
+
+// factors returns the Porter-Duff fractions of the source and destination
+// kept for source alpha as and destination alpha ab.
+func (op CompositeOp) factors(as, ab float64) (fa, fb float64) {
+	switch op {
+	case CompositeClear:
+		return 0, 0
+	case CompositeCopy:
+		return 1, 0
+	case CompositeSourceIn:
+		return ab, 0
+	case CompositeSourceOut:
+		return 1 - ab, 0
+	case CompositeSourceAtop:
+		return ab, 1 - as
+	case CompositeDestinationOver:
+		return 1 - ab, 1
+	case CompositeDestinationIn:
+		return 0, as
+	case CompositeDestinationOut:
+		return 0, 1 - as
+	case CompositeDestinationAtop:
+		return 1 - ab, as
+	case CompositeXor:
+		return 1 - ab, 1 - as
+	case CompositeLighter:
+		return 1, 1
+	}
+	return 1, 1 - as
+}
+
+// unbounded reports whether op changes the destination where there is no
+// source.
+func (op CompositeOp) unbounded() bool {
+	switch op {
+	case CompositeCopy, CompositeSourceIn, CompositeSourceOut,
+		CompositeDestinationIn, CompositeDestinationAtop:
+		return true
+	}
+	return false
+}
+
+// sourceOver reports whether drawing is plain source-over with no blending.
+func (c *Canvas) sourceOver() bool {
+	return c.blendMode == BlendNormal && c.compositeOp == CompositeSourceOver
+}
+
+// compositeLayer draws layer onto the canvas, where its bounds place it,
+// through the clip mask with the blend mode and operator. Outside the layer
+// the source is transparent.
+func (c *Canvas) compositeLayer(layer *image.RGBA) {
+	if c.sourceOver() {
+		r := layer.Rect
+		if c.mask == nil {
+			draw.Draw(c.im, r, layer, r.Min, draw.Over)
+		} else {
+			draw.DrawMask(c.im, r, layer, r.Min, c.mask, r.Min, draw.Over)
+		}
+		return
+	}
+	unbounded := c.compositeOp.unbounded()
+	b := c.im.Bounds()
+	if !unbounded {
+		b = b.Intersect(layer.Rect)
+	}
+	var transparent [4]uint8
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			s := transparent[:]
+			if (image.Point{x, y}).In(layer.Rect) {
+				j := layer.PixOffset(x, y)
+				s = layer.Pix[j : j+4]
+			}
+			if s[3] == 0 && !unbounded {
+				continue
+			}
+			clip := uint32(0xffff)
+			if c.mask != nil {
+				clip = uint32(c.mask.AlphaAt(x, y).A) * 0x101
+			}
+			i := c.im.PixOffset(x, y)
+			compositePixel(c.im.Pix[i:i+4], uint32(s[0])*0x101, uint32(s[1])*0x101,
+				uint32(s[2])*0x101, uint32(s[3])*0x101, 0xffff, clip, c.blendMode, c.compositeOp)
+		}
+	}
+}
+
+// compositePixel blends the premultiplied 16 bit source color sr, sg, sb,
+// sa, with its alpha scaled by the shape coverage, into the RGBA pixel d and
+// combines the two with op. clip is how much of the result replaces d.
+func compositePixel(d []uint8, sr, sg, sb, sa, coverage, clip uint32, mode BlendMode, op CompositeOp) {
+	const m = 1<<16 - 1
+	if op == CompositeClear {
+		clip = clip * (sa * coverage / m) / m
+	}
+	if clip == 0 {
+		return
+	}
+	as := float64(sa) / m * float64(coverage) / m
+	ab := float64(d[3]) / 255
+	fa, fb := op.factors(as, ab)
+	if as == 0 && fb == 1 {
+		return
+	}
+	var cs, cb [3]float64
+	for k, v := range [3]uint32{sr, sg, sb} {
+		if sa > 0 {
+			cs[k] = float64(v) / float64(sa)
+		}
+		if d[3] > 0 {
+			cb[k] = float64(d[k]) / float64(d[3])
+		}
+	}
+	if mode != BlendNormal {
+		mixed := blendColors(mode, cb, cs)
+		for k := range cs {
+			cs[k] = (1-ab)*cs[k] + ab*mixed[k]
+		}
+	}
+	t := float64(clip) / m
+	for k := range cs {
+		v := fa*as*cs[k] + fb*float64(d[k])/255
+		d[k] = uint8(math.Min(1, t*v+(1-t)*float64(d[k])/255)*255 + 0.5)
+	}
+	a := fa*as + fb*ab
+	d[3] = uint8(math.Min(1, t*a+(1-t)*ab)*255 + 0.5)
+}
@@ -0,0 +1,89 @@
+package drawlib
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCompositeFactors(t *testing.T) {
+	const as, ab = 0.25, 0.5
+	for _, test := range []struct {
+		op     CompositeOp
+		fa, fb float64
+	}{
+		{CompositeSourceOver, 1, 0.75},
+		{CompositeClear, 0, 0},
+		{CompositeCopy, 1, 0},
+		{CompositeSourceIn, 0.5, 0},
+		{CompositeSourceOut, 0.5, 0},
+		{CompositeSourceAtop, 0.5, 0.75},
+		{CompositeDestinationOver, 0.5, 1},
+		{CompositeDestinationIn, 0, 0.25},
+		{CompositeDestinationOut, 0, 0.75},
+		{CompositeDestinationAtop, 0.5, 0.25},
+		{CompositeXor, 0.5, 0.75},
+		{CompositeLighter, 1, 1},
+	} {
+		if fa, fb := test.op.factors(as, ab); fa != test.fa || fb != test.fb {
+			t.Errorf("%v: factors %v, %v, want %v, %v", test.op, fa, fb, test.fa, test.fb)
+		}
+	}
+}
+
+func TestCompositePixel(t *testing.T) {
+	const m = 0xffff
+	for _, test := range []struct {
+		op       CompositeOp
+		d, want  [4]uint8
+		coverage uint32
+	}{
+		// red at alpha 128 onto opaque blue
+		{CompositeSourceOver, [4]uint8{0, 0, 255, 255}, [4]uint8{128, 0, 127, 255}, m},
+		{CompositeDestinationOver, [4]uint8{0, 0, 255, 255}, [4]uint8{0, 0, 255, 255}, m},
+		{CompositeSourceAtop, [4]uint8{0, 0, 255, 255}, [4]uint8{128, 0, 127, 255}, m},
+		{CompositeDestinationOut, [4]uint8{0, 0, 255, 255}, [4]uint8{0, 0, 127, 127}, m},
+		{CompositeXor, [4]uint8{0, 0, 255, 255}, [4]uint8{0, 0, 127, 127}, m},
+		{CompositeCopy, [4]uint8{0, 0, 255, 255}, [4]uint8{128, 0, 0, 128}, m},
+		{CompositeLighter, [4]uint8{0, 0, 255, 255}, [4]uint8{128, 0, 255, 255}, m},
+		// onto transparent
+		{CompositeSourceIn, [4]uint8{}, [4]uint8{}, m},
+		{CompositeSourceOut, [4]uint8{}, [4]uint8{128, 0, 0, 128}, m},
+		// clear only erases as much as the shape covers
+		{CompositeClear, [4]uint8{0, 0, 255, 255}, [4]uint8{0, 0, 191, 191}, m / 2},
+		{CompositeSourceOver, [4]uint8{0, 0, 255, 255}, [4]uint8{0, 0, 255, 255}, 0},
+	} {
+		d := test.d
+		compositePixel(d[:], 0x8080, 0, 0, 0x8080, test.coverage, m, BlendNormal, test.op)
+		if d != test.want {
+			t.Errorf("%v onto %v with coverage %#x: got %v, want %v", test.op, test.d, test.coverage, d, test.want)
+		}
+	}
+}
+
+func TestCompositeOpUnbounded(t *testing.T) {
+	c := NewCanvas(20, 10)
+	c.Background(0, 0, 255)
+	c.DrawRectangle(0, 0, 20, 10)
+	c.Clip()
+	c.SetCompositeOp(CompositeCopy)
+	c.SetRGB(1, 0, 0)
+	c.DrawRectangle(0, 0, 10, 10)
+	c.Fill()
+	if got := c.im.RGBAAt(5, 5); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("inside the shape: %v, want red", got)
+	}
+	if got := c.im.RGBAAt(15, 5); got != (color.RGBA{}) {
+		t.Errorf("outside the shape: %v, want cleared", got)
+	}
+
+	c.SetCompositeOp(CompositeDestinationOver)
+	c.SetRGB(0, 1, 0)
+	c.DrawRectangle(0, 0, 20, 10)
+	c.Fill()
+	if got := c.im.RGBAAt(5, 5); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("destination-over covered the canvas: %v", got)
+	}
+	if got := c.im.RGBAAt(15, 5); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("destination-over behind nothing: %v, want green", got)
+	}
+}
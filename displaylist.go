@@ -0,0 +1,526 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/golang/freetype/raster"
+	"golang.org/x/image/math/fixed"
+)
+
+type (
+	// DisplayList holds flattened Canvas drawing operations in device space.
+	// Text is kept as glyph outlines, so a list needs no fonts to replay.
+	DisplayList struct {
+		Width, Height int
+		ops           []*displayOp
+	}
+	displayOp struct {
+		Op         string          `json:"op"`
+		Path       []fixed.Int26_6 `json:"path,omitempty"`
+		Closed     []bool          `json:"closed,omitempty"`
+		Paint      *displayPaint   `json:"paint,omitempty"`
+		FillRule   FillRule        `json:"fillRule,omitempty"`
+		LineWidth  float64         `json:"lineWidth,omitempty"`
+		LineCap    LineCap         `json:"lineCap,omitempty"`
+		LineJoin   LineJoin        `json:"lineJoin,omitempty"`
+		MiterLimit float64         `json:"miterLimit,omitempty"`
+		Dashes     []float64       `json:"dashes,omitempty"`
+		DashOffset float64         `json:"dashOffset,omitempty"`
+		Blend      BlendMode       `json:"blend,omitempty"`
+		Composite  CompositeOp     `json:"composite,omitempty"`
+		Matrix     *Matrix         `json:"matrix,omitempty"`
+		Image      []byte          `json:"image,omitempty"`
+		im         image.Image
+	}
+	displayPaint struct {
+		Kind   string        `json:"kind"`
+		Color  color.NRGBA   `json:"color"`
+		Coords []float64     `json:"coords,omitempty"`
+		Stops  []displayStop `json:"stops,omitempty"`
+		Image  []byte        `json:"image,omitempty"`
+		Repeat RepeatOp      `json:"repeat,omitempty"`
+		Spread Spread        `json:"spread,omitempty"`
+		Matrix *Matrix       `json:"matrix,omitempty"`
+		im     image.Image
+	}
+	displayStop struct {
+		Pos   float64     `json:"pos"`
+		Color color.NRGBA `json:"color"`
+	}
+	displayListData struct {
+		Width  int          `json:"width"`
+		Height int          `json:"height"`
+		Ops    []*displayOp `json:"ops"`
+	}
+	displayRecorder struct {
+		list *DisplayList
+	}
+	transformedPattern struct {
+		p       Pattern
+		inverse *Matrix
+		opacity float64
+	}
+)
+
+// RecordDisplayList runs f on a new width x height canvas and returns the
+// drawing operations it made.
+func RecordDisplayList(width, height int, f func(*Canvas)) *DisplayList {
+	dl := &DisplayList{Width: width, Height: height}
+	c := NewCanvas(width, height)
+	c.recorder = &displayRecorder{dl}
+	f(c)
+	return dl
+}
+
+func (c *Canvas) DrawDisplayList(dl *DisplayList) *Canvas {
+	return c.DrawDisplayListTransformed(dl, Identity(), 1)
+}
+
+// DrawDisplayListTransformed replays dl through m and then the current
+// matrix, scaling the alpha of everything drawn by opacity. The clips of
+// the list apply inside the canvas clip, and end with the replay.
+func (c *Canvas) DrawDisplayListTransformed(dl *DisplayList, m *Matrix, opacity float64) *Canvas {
+	full := m.Multiply(*c.matrix)
+	base := c.saveClip()
+	for _, op := range dl.ops {
+		c.replay(dl, op, full, opacity, base)
+	}
+	c.restoreClip(base)
+	return c
+}
+
+// replay draws op, with base the canvas clip the list's clips apply in.
+func (c *Canvas) replay(dl *DisplayList, op *displayOp, m *Matrix, opacity float64, base savedClip) {
+	state := *c
+	c.matrix = Identity()
+	c.hasCurrent = false
+	if op.Blend != BlendNormal {
+		c.blendMode = op.Blend
+	}
+	if op.Composite != CompositeSourceOver {
+		c.compositeOp = op.Composite
+	}
+	switch op.Op {
+	case "fill":
+		c.fillPath = transformRasterPath(op.Path, m)
+		c.fillRule = op.FillRule
+		c.fillPattern = op.Paint.pattern(m, opacity)
+		c.FillPreserve()
+	case "stroke":
+		scale := math.Sqrt(math.Abs(m.XX*m.YY - m.XY*m.YX))
+		c.path = *pathFromFixed(transformRasterPath(op.Path, m), op.Closed)
+		c.lineWidth = op.LineWidth * scale
+		c.lineCap = op.LineCap
+		c.lineJoin = op.LineJoin
+		c.miterLimit = op.MiterLimit
+		c.dashes = nil
+		for _, d := range op.Dashes {
+			c.dashes = append(c.dashes, d*scale)
+		}
+		c.dashOffset = op.DashOffset * scale
+		c.strokePattern = op.Paint.pattern(m, opacity)
+		c.StrokePreserve()
+	case "clip":
+		c.fillPath = transformRasterPath(op.Path, m)
+		c.fillRule = op.FillRule
+		c.ClipPreserve()
+	case "resetClip":
+		c.restoreClip(base)
+	case "clear":
+		if isIdentity(m) && opacity == 1 {
+			c.clearSrc = image.NewUniform(op.Paint.Color)
+			c.Clear()
+		} else {
+			w, h := float64(dl.Width), float64(dl.Height)
+			var path raster.Path
+			path.Start(Fixp(m.TransformPoint(0, 0)))
+			path.Add1(Fixp(m.TransformPoint(w, 0)))
+			path.Add1(Fixp(m.TransformPoint(w, h)))
+			path.Add1(Fixp(m.TransformPoint(0, h)))
+			path.Add1(Fixp(m.TransformPoint(0, 0)))
+			c.fillPath = path
+			c.fillRule = FillRuleWinding
+			c.fillPattern = op.Paint.pattern(m, opacity)
+			c.FillPreserve()
+		}
+	case "image":
+		c.matrix = op.Matrix.Multiply(*m)
+		im := op.im
+		if opacity < 1 {
+			im = fadeImage(im, opacity)
+		}
+		c.DrawImage(im, 0, 0)
+	}
+	clip := c.saveClip()
+	*c = state
+	c.mask, c.clips = clip.mask, clip.clips
+}
+
+func (r *displayRecorder) add(op *displayOp) {
+	r.list.ops = append(r.list.ops, op)
+}
+
+func (r *displayRecorder) fill(c *Canvas, path raster.Path, pattern Pattern) {
+	r.add(&displayOp{
+		Op:        "fill",
+		Path:      append([]fixed.Int26_6(nil), path...),
+		Paint:     newDisplayPaint(c, pattern),
+		FillRule:  c.fillRule,
+		Blend:     c.blendMode,
+		Composite: c.compositeOp,
+	})
+}
+
+func (r *displayRecorder) stroke(c *Canvas, path raster.Path, closed []bool, pattern Pattern) {
+	r.add(&displayOp{
+		Op:         "stroke",
+		Path:       append([]fixed.Int26_6(nil), path...),
+		Closed:     closed,
+		Paint:      newDisplayPaint(c, pattern),
+		LineWidth:  c.lineWidth,
+		LineCap:    c.lineCap,
+		LineJoin:   c.lineJoin,
+		MiterLimit: c.miterLimit,
+		Dashes:     append([]float64(nil), c.dashes...),
+		DashOffset: c.dashOffset,
+		Blend:      c.blendMode,
+		Composite:  c.compositeOp,
+	})
+}
+
+func (r *displayRecorder) clip(c *Canvas, path raster.Path) {
+	r.add(&displayOp{
+		Op:       "clip",
+		Path:     append([]fixed.Int26_6(nil), path...),
+		FillRule: c.fillRule,
+	})
+}
+
+func (r *displayRecorder) resetClip(c *Canvas) {
+	r.add(&displayOp{Op: "resetClip"})
+}
+
+func (r *displayRecorder) clear(c *Canvas) {
+	r.add(&displayOp{
+		Op:    "clear",
+		Paint: newDisplayPaint(c, NewSolidPattern(c.clearSrc.C)),
+	})
+}
+
+func (r *displayRecorder) image(c *Canvas, im image.Image, m *Matrix) {
+	matrix := *m
+	r.add(&displayOp{Op: "image", Matrix: &matrix, Blend: c.blendMode, Composite: c.compositeOp, im: im})
+}
+
+func (r *displayRecorder) text(c *Canvas, s string, x, y float64) {
+	if c.font == nil {
+		if im, m := c.textImage(s, x, y); !im.Rect.Empty() {
+			r.image(c, im, m)
+		}
+		return
+	}
+	rule := c.fillRule
+	c.fillRule = FillRuleWinding
+	r.fill(c, c.stringPath(s, x, y), NewSolidPattern(c.color))
+	c.fillRule = rule
+}
+
+func newDisplayPaint(c *Canvas, pattern Pattern) *displayPaint {
+	newStops := func(s stops) []displayStop {
+		result := make([]displayStop, len(s))
+		for i, stop := range s {
+			result[i] = displayStop{stop.pos, color.NRGBAModel.Convert(stop.color).(color.NRGBA)}
+		}
+		return result
+	}
+	// gradients keep their own coordinates and are mapped to device space
+	gradient := func(kind string, g *gradient, coords ...float64) *displayPaint {
+		paint := &displayPaint{Kind: kind, Coords: coords, Stops: newStops(g.stops), Spread: g.spread}
+		if m := g.deviceMatrix(c.matrix); !isIdentity(m) {
+			paint.Matrix = m
+		}
+		return paint
+	}
+	switch p := pattern.(type) {
+	case *solidPattern:
+		return &displayPaint{Kind: "solid", Color: color.NRGBAModel.Convert(p.color).(color.NRGBA)}
+	case *linearGradient:
+		return gradient("linear", &p.gradient, p.x0, p.y0, p.x1, p.y1)
+	case *radialGradient:
+		return gradient("radial", &p.gradient, p.c0.x, p.c0.y, p.c0.r, p.c1.x, p.c1.y, p.c1.r)
+	case *conicGradient:
+		return gradient("conic", &p.gradient, p.cx, p.cy, p.angle)
+	case *surfacePattern:
+		return &displayPaint{Kind: "surface", im: p.im, Repeat: p.op}
+	}
+	// other patterns are sampled over the whole canvas
+	sampled := c.devicePattern(pattern)
+	im := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			im.Set(x, y, sampled.ColorAt(x, y))
+		}
+	}
+	return &displayPaint{Kind: "surface", im: im, Repeat: RepeatNone}
+}
+
+// pattern returns the paint as a Pattern in the space mapped by m.
+func (p *displayPaint) pattern(m *Matrix, opacity float64) Pattern {
+	fade := func(c color.NRGBA) color.NRGBA {
+		c.A = uint8(float64(c.A)*opacity + 0.5)
+		return c
+	}
+	addStops := func(pattern Gradient) Gradient {
+		g := pattern.(TransformableGradient)
+		for _, s := range p.Stops {
+			g.AddColorStop(s.Pos, fade(s.Color))
+		}
+		g.SetSpread(p.Spread)
+		if p.Matrix != nil {
+			g.SetMatrix(p.Matrix.Multiply(*m))
+		} else {
+			g.SetMatrix(m)
+		}
+		return g
+	}
+	switch p.Kind {
+	case "solid":
+		return NewSolidPattern(fade(p.Color))
+	case "linear":
+		return addStops(NewLinearGradient(p.Coords[0], p.Coords[1], p.Coords[2], p.Coords[3]))
+	case "radial":
+		return addStops(NewRadialGradient(p.Coords[0], p.Coords[1], p.Coords[2], p.Coords[3], p.Coords[4], p.Coords[5]))
+	case "conic":
+		return addStops(NewConicGradient(p.Coords[0], p.Coords[1], p.Coords[2]))
+	case "surface":
+		pattern := NewSurfacePattern(p.im, p.Repeat)
+		if isIdentity(m) && opacity == 1 {
+			return pattern
+		}
+		return &transformedPattern{pattern, m.Invert(), opacity}
+	}
+	return NewSolidPattern(color.Transparent)
+}
+
+func (p *transformedPattern) ColorAt(x, y int) color.Color {
+	fx, fy := p.inverse.TransformPoint(float64(x)+0.5, float64(y)+0.5)
+	c := color.NRGBAModel.Convert(p.p.ColorAt(int(math.Floor(fx)), int(math.Floor(fy)))).(color.NRGBA)
+	c.A = uint8(float64(c.A)*p.opacity + 0.5)
+	return c
+}
+
+func (dl *DisplayList) data() *displayListData {
+	for _, op := range dl.ops {
+		if op.im != nil && op.Image == nil {
+			op.Image = encodeDisplayImage(op.im)
+		}
+		if op.Paint != nil && op.Paint.im != nil && op.Paint.Image == nil {
+			op.Paint.Image = encodeDisplayImage(op.Paint.im)
+		}
+	}
+	return &displayListData{dl.Width, dl.Height, dl.ops}
+}
+
+func (dl *DisplayList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.data())
+}
+
+func (dl *DisplayList) UnmarshalJSON(b []byte) error {
+	var data displayListData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if err := data.validate(); err != nil {
+		return err
+	}
+	dl.Width, dl.Height, dl.ops = data.Width, data.Height, data.Ops
+	return nil
+}
+
+func (dl *DisplayList) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(dl.data())
+	return b.Bytes(), err
+}
+
+func (dl *DisplayList) UnmarshalBinary(b []byte) error {
+	var data displayListData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return err
+	}
+	if err := data.validate(); err != nil {
+		return err
+	}
+	dl.Width, dl.Height, dl.ops = data.Width, data.Height, data.Ops
+	return nil
+}
+
+func encodeDisplayImage(im image.Image) []byte {
+	var b bytes.Buffer
+	png.Encode(&b, im)
+	return b.Bytes()
+}
+
+// validate checks decoded operations and decodes their images, so that a
+// list read from elsewhere cannot make replay panic.
+func (data *displayListData) validate() error {
+	for i, op := range data.Ops {
+		if op == nil {
+			return fmt.Errorf("display list op %d is missing", i)
+		}
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("display list op %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (op *displayOp) validate() error {
+	switch op.Op {
+	case "fill", "clip":
+		if err := validatePath(op.Path); err != nil {
+			return err
+		}
+	case "stroke":
+		if err := validatePath(op.Path); err != nil {
+			return err
+		}
+		starts := 0
+		walkPath(op.Path, func(kind int, points []*Vector) {
+			if kind == 0 {
+				starts++
+			}
+		})
+		if len(op.Closed) > starts {
+			return errors.New("more closed flags than subpaths")
+		}
+		if !finite(op.LineWidth, op.MiterLimit, op.DashOffset) || !finite(op.Dashes...) {
+			return errors.New("bad stroke")
+		}
+		for _, d := range op.Dashes {
+			if d < 0 {
+				return errors.New("negative dash")
+			}
+		}
+	case "resetClip", "clear":
+	case "image":
+		if op.Matrix == nil || !finite(op.Matrix.XX, op.Matrix.YX, op.Matrix.XY, op.Matrix.YY, op.Matrix.X0, op.Matrix.Y0) {
+			return errors.New("bad image matrix")
+		}
+		im, err := decodeDisplayImage(op.Image)
+		if err != nil {
+			return err
+		}
+		op.im = im
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	switch op.Op {
+	case "fill", "stroke", "clear":
+		if op.Paint == nil {
+			return fmt.Errorf("%s has no paint", op.Op)
+		}
+		return op.Paint.validate()
+	}
+	return nil
+}
+
+func (p *displayPaint) validate() error {
+	coords := map[string]int{"solid": 0, "surface": 0, "linear": 4, "radial": 6, "conic": 3}
+	n, ok := coords[p.Kind]
+	if !ok {
+		return fmt.Errorf("unknown paint %q", p.Kind)
+	}
+	if len(p.Coords) != n || !finite(p.Coords...) {
+		return fmt.Errorf("%s paint needs %d coordinates", p.Kind, n)
+	}
+	for _, s := range p.Stops {
+		if !finite(s.Pos) {
+			return errors.New("bad gradient stop")
+		}
+	}
+	if m := p.Matrix; m != nil && !finite(m.XX, m.YX, m.XY, m.YY, m.X0, m.Y0) {
+		return errors.New("bad paint matrix")
+	}
+	if p.Kind == "surface" {
+		im, err := decodeDisplayImage(p.Image)
+		if err != nil {
+			return err
+		}
+		if im.Bounds().Empty() {
+			return errors.New("empty surface paint")
+		}
+		p.im = im
+	}
+	return nil
+}
+
+// validatePath checks the segment tags and lengths of a raster path.
+func validatePath(p []fixed.Int26_6) error {
+	sizes := map[fixed.Int26_6]int{0: 4, 1: 4, 2: 6, 3: 8}
+	for i := 0; i < len(p); {
+		n, ok := sizes[p[i]]
+		if !ok || i+n > len(p) || p[i+n-1] != p[i] {
+			return fmt.Errorf("bad path at %d", i)
+		}
+		i += n
+	}
+	return nil
+}
+
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeDisplayImage(b []byte) (image.Image, error) {
+	return png.Decode(bytes.NewReader(b))
+}
+
+func transformRasterPath(p []fixed.Int26_6, m *Matrix) raster.Path {
+	var result raster.Path
+	if isIdentity(m) {
+		return append(result, p...)
+	}
+	walkPath(p, func(op int, points []*Vector) {
+		var f [3]fixed.Point26_6
+		for i, point := range points {
+			f[i] = Fixp(m.TransformPoint(point.X, point.Y))
+		}
+		switch op {
+		case 0:
+			result.Start(f[0])
+		case 1:
+			result.Add1(f[0])
+		case 2:
+			result.Add2(f[0], f[1])
+		case 3:
+			result.Add3(f[0], f[1], f[2])
+		}
+	})
+	return result
+}
+
+func isIdentity(m *Matrix) bool {
+	return *m == Matrix{1, 0, 0, 1, 0, 0}
+}
+
+func fadeImage(im image.Image, opacity float64) image.Image {
+	result := ImageToRGBA(im)
+	for i := range result.Pix {
+		result.Pix[i] = uint8(float64(result.Pix[i])*opacity + 0.5)
+	}
+	return result
+}
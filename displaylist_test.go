@@ -0,0 +1,170 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func drawDisplayListScene(c *Canvas) {
+	c.Background(255)
+	g := NewLinearGradient(0, 0, 60, 0)
+	g.AddColorStop(0, color.RGBA{255, 0, 0, 255})
+	g.AddColorStop(1, color.RGBA{0, 0, 255, 255})
+	c.SetFillStyle(g)
+	c.DrawRectangle(5, 5, 50, 30)
+	c.Fill()
+	c.SetRGB(0, 0.5, 0)
+	c.SetLineWidth(3)
+	c.SetDash(4, 2)
+	c.DrawCircle(40, 40, 15)
+	c.Stroke()
+	c.DrawRectangle(0, 0, 30, 60)
+	c.Clip()
+	im := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range im.Pix {
+		im.Pix[i] = 200
+	}
+	c.DrawImage(im, 20, 20)
+}
+
+func TestDisplayListRoundTrip(t *testing.T) {
+	dl := RecordDisplayList(60, 60, drawDisplayListScene)
+	want := NewCanvas(60, 60)
+	want.DrawDisplayList(dl)
+
+	direct := NewCanvas(60, 60)
+	drawDisplayListScene(direct)
+	if !bytes.Equal(want.im.Pix, direct.im.Pix) {
+		t.Error("replay differs from drawing directly")
+	}
+
+	js, err := json.Marshal(dl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := dl.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON, fromBinary DisplayList
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromBinary.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	for name, decoded := range map[string]*DisplayList{"json": &fromJSON, "binary": &fromBinary} {
+		if decoded.Width != 60 || decoded.Height != 60 || len(decoded.ops) != len(dl.ops) {
+			t.Errorf("%s: got %dx%d with %d ops, want 60x60 with %d", name,
+				decoded.Width, decoded.Height, len(decoded.ops), len(dl.ops))
+			continue
+		}
+		got := NewCanvas(60, 60)
+		got.DrawDisplayList(decoded)
+		if !bytes.Equal(got.im.Pix, want.im.Pix) {
+			t.Errorf("%s: replay differs from the recorded list", name)
+		}
+	}
+}
+
+func TestDisplayListRejectsBadInput(t *testing.T) {
+	for _, js := range []string{
+		`{"ops":[{"op":"fill","path":[0,0,0]}]}`,
+		`{"ops":[{"op":"fill","path":[0,0,0,0],"paint":{"kind":"solid"}},{"op":"bogus"}]}`,
+		`{"ops":[{"op":"fill","path":[0,0,0,0,7,0,0,7],"paint":{"kind":"solid"}}]}`,
+		`{"ops":[{"op":"fill","path":[1,0,0],"paint":{"kind":"solid"}}]}`,
+		`{"ops":[{"op":"fill","path":[2,0,0,0,0,1],"paint":{"kind":"solid"}}]}`,
+		`{"ops":[{"op":"fill","paint":{"kind":"linear","coords":[0,0]}}]}`,
+		`{"ops":[{"op":"fill","paint":{"kind":"radial","coords":[0,0,1,0,0]}}]}`,
+		`{"ops":[{"op":"fill","paint":{"kind":"pattern"}}]}`,
+		`{"ops":[{"op":"fill","paint":{"kind":"surface","image":"AAAA"}}]}`,
+		`{"ops":[{"op":"stroke","paint":{"kind":"solid"},"dashes":[-1,2]}]}`,
+		`{"ops":[{"op":"stroke","path":[0,0,0,0],"closed":[true,false],"paint":{"kind":"solid"}}]}`,
+		`{"ops":[{"op":"clear"}]}`,
+		`{"ops":[{"op":"image","image":"AAAA","matrix":{"XX":1,"YY":1}}]}`,
+		`{"ops":[{"op":"image"}]}`,
+		`{"ops":[null]}`,
+	} {
+		var dl DisplayList
+		if err := json.Unmarshal([]byte(js), &dl); err == nil {
+			t.Errorf("%s: decoded without error", js)
+		}
+	}
+
+	var b bytes.Buffer
+	data := displayListData{Ops: []*displayOp{{Op: "stroke", Path: []fixed.Int26_6{0, 0, 0, 0}}}}
+	if err := gob.NewEncoder(&b).Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+	var dl DisplayList
+	if err := dl.UnmarshalBinary(b.Bytes()); err == nil {
+		t.Error("binary stroke without paint decoded without error")
+	}
+}
+
+func TestDisplayListClipInsideCanvasClip(t *testing.T) {
+	dl := RecordDisplayList(40, 40, func(c *Canvas) {
+		c.DrawRectangle(0, 0, 40, 20)
+		c.Clip()
+		c.SetRGB(1, 0, 0)
+		c.DrawRectangle(0, 0, 40, 40)
+		c.Fill()
+		c.ResetClip()
+		c.SetRGB(0, 0, 1)
+		c.DrawRectangle(0, 30, 40, 10)
+		c.Fill()
+		// left open at the end of the list
+		c.DrawRectangle(0, 0, 10, 40)
+		c.Clip()
+	})
+	c := NewCanvas(40, 40)
+	c.DrawRectangle(0, 0, 20, 40)
+	c.Clip()
+	c.DrawDisplayList(dl)
+	check := func(when string, want map[image.Point]color.RGBA) {
+		for p, w := range want {
+			if got := c.im.RGBAAt(p.X, p.Y); got != w {
+				t.Errorf("%s: pixel %v is %v, want %v", when, p, got, w)
+			}
+		}
+	}
+	check("after the replay", map[image.Point]color.RGBA{
+		{10, 10}: {255, 0, 0, 255},
+		{30, 10}: {},
+		{10, 35}: {0, 0, 255, 255},
+		{30, 35}: {},
+	})
+	c.SetRGB(0, 1, 0)
+	c.DrawRectangle(0, 0, 40, 40)
+	c.Fill()
+	check("drawing after the replay", map[image.Point]color.RGBA{
+		{15, 35}: {0, 255, 0, 255},
+		{30, 35}: {},
+	})
+}
+
+func TestDisplayListBitmapTextCropped(t *testing.T) {
+	draw := func(c *Canvas) {
+		c.SetRGB(0, 0, 0)
+		c.DrawString("Hi", 20, 40)
+	}
+	dl := RecordDisplayList(200, 100, draw)
+	if len(dl.ops) != 1 || dl.ops[0].Op != "image" {
+		t.Fatalf("recorded %d ops, want the text as one image", len(dl.ops))
+	}
+	if b := dl.ops[0].im.Bounds(); b.Dx() >= 50 || b.Dy() >= 50 {
+		t.Errorf("text image is %dx%d, want it cropped to the text", b.Dx(), b.Dy())
+	}
+	got, want := NewCanvas(200, 100), NewCanvas(200, 100)
+	got.DrawDisplayList(dl)
+	draw(want)
+	if !bytes.Equal(got.im.Pix, want.im.Pix) {
+		t.Error("replayed text differs from drawing it directly")
+	}
+}
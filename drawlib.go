@@ -3,13 +3,10 @@ package drawlib
 import (
 	"image"
 	"image/color"
-	"image/draw"
 	"log"
 	"sync"
 	"time"
 
-	"github.com/ATTHDEV/shiny/driver"
-	"github.com/ATTHDEV/shiny/screen"
 	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/mouse"
@@ -25,6 +22,9 @@ type option struct {
 	width, height int
 	title         string
 	x, y          int
+	headless      bool
+	frames        int
+	backend       Backend
 }
 
 func Option() *option {
@@ -50,17 +50,29 @@ func (o *option) Location(x, y int) *option {
 	return o
 }
 
+// Headless runs the sketch without a window. Init, Render and RenderLoop are
+// driven against Canvas with a fixed clock of one tick per frame; the run
+// stops after frames frames, or on Quit when frames is 0.
+func (o *option) Headless(frames int) *option {
+	o.headless = true
+	o.frames = frames
+	return o
+}
+
+func (o *option) Backend(b Backend) *option {
+	o.backend = b
+	return o
+}
+
 type (
 	updateEvent struct {
 		dt float64
 	}
 	Drawlib struct {
 		mutex                 *sync.Mutex
-		options               *screen.WindowOptions
-		buffer                screen.Buffer
-		screen                screen.Screen
-		window                screen.Window
-		texture               screen.Texture
+		options               *WindowOptions
+		backend               Backend
+		window                Window
 		rect                  image.Rectangle
 		drawState             int8
 		Canvas                *Canvas
@@ -72,6 +84,8 @@ type (
 		mouseIsPressY         int
 		defaultCloseOperation bool
 		autoscale             bool
+		headless              bool
+		frames                int
 		publish               bool
 		renderCallback        *func()
 		renderLoopCallback    *func(float64)
@@ -161,88 +175,96 @@ func (d *Drawlib) OnWindowsClose(f func()) {
 }
 
 func New(o ...*option) *Drawlib {
-	var options *screen.WindowOptions
+	options := &WindowOptions{
+		Title: "Drawlib Windows", X: -1, Y: -1, Width: 600, Height: 600,
+	}
+	var backend Backend = shinyBackend{}
+	var headless bool
+	var frames int
 	if len(o) == 1 {
-		options = screen.NewWindowOptions(
-			screen.Title(o[0].title),
-			screen.Dimensions(o[0].width, o[0].height),
-			screen.Location(o[0].x, o[0].y),
-		)
-	} else {
-		options = screen.NewWindowOptions(
-			screen.Title("Drawlib Windows"),
-			screen.Dimensions(600, 600),
-		)
+		options.Title = o[0].title
+		options.Width, options.Height = o[0].width, o[0].height
+		options.X, options.Y = o[0].x, o[0].y
+		headless = o[0].headless
+		frames = o[0].frames
+		if o[0].backend != nil {
+			backend = o[0].backend
+		}
 	}
 	return &Drawlib{
 		options:               options,
+		backend:               backend,
 		Canvas:                NewCanvas(options.Width, options.Height),
 		defaultCloseOperation: true,
-		quit:                  make(chan bool),
+		headless:              headless,
+		frames:                frames,
+		quit:                  make(chan bool, 1),
 	}
 }
 
 func (d *Drawlib) Start() {
-	driver.Main(func(s screen.Screen) {
-		w, err := s.NewWindow(d.options)
-
-		if err != nil {
-			log.Fatal(err)
-		}
+	if d.headless {
+		d.startHeadless()
+		return
+	}
+	err := d.backend.Main(*d.options, func(w Window) {
 		defer w.Release()
 
 		d.mutex = &sync.Mutex{}
-		d.screen = s
 		d.window = w
 		d.rect = image.Rect(0, 0, d.options.Width, d.options.Height)
 
-		d.buffer, err = s.NewBuffer(image.Point{d.options.Width, d.options.Height})
-		if err != nil {
-			panic(err)
-		}
-
-		d.texture, err = d.screen.NewTexture(d.buffer.Bounds().Max)
-		if err != nil {
-			panic(err)
-		}
-
 		if d.initCallback != nil {
 			(*d.initCallback)()
 		}
 
-		go func() {
-			ticker := time.NewTicker(tickDuration)
-			timeStart := time.Now().UnixNano()
-			var tickerC <-chan time.Time
-			for {
-				tickerC = ticker.C
-				select {
-				case <-d.quit:
-					ticker.Stop()
-					break
-				case <-tickerC:
-					if d.keyIsPressCallback != nil {
-						if d.keyIsPress {
-							(*d.keyIsPressCallback)(d.keyIsPressCode)
-						}
-					}
-					if d.mouseIsPressCallback != nil {
-						if d.mouseIsPress {
-							(*d.mouseIsPressCallback)(d.mouseIsPressButton, d.mouseIsPressX, d.mouseIsPressY)
-						}
-					}
-					now := time.Now().UnixNano()
-					delta := float64(now-timeStart) / 1000000000
-					timeStart = now
-					if d.renderLoopCallback != nil {
-						(*d.renderLoopCallback)(delta)
-					}
-					w.Send(updateEvent{})
-				}
-			}
-		}()
+		if b, ok := d.backend.(steppedBackend); !ok || !b.steppedClock() {
+			go d.clock(w)
+		}
 		d.eventLoop()
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// clock runs a frame and presents it on every tick of the wall clock until
+// the event loop quits.
+func (d *Drawlib) clock(w Window) {
+	ticker := time.NewTicker(tickDuration)
+	timeStart := time.Now().UnixNano()
+	for {
+		select {
+		case <-d.quit:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			delta := float64(now-timeStart) / 1000000000
+			timeStart = now
+			d.frame(delta)
+			w.Send(updateEvent{})
+		}
+	}
+}
+
+// frame runs the per-frame callbacks, dt seconds after the last frame.
+func (d *Drawlib) frame(dt float64) {
+	if d.keyIsPressCallback != nil {
+		if d.keyIsPress {
+			(*d.keyIsPressCallback)(d.keyIsPressCode)
+		}
+	}
+	if d.mouseIsPressCallback != nil {
+		if d.mouseIsPress {
+			(*d.mouseIsPressCallback)(d.mouseIsPressButton, d.mouseIsPressX, d.mouseIsPressY)
+		}
+	}
+	d.mutex.Lock()
+	if d.renderLoopCallback != nil {
+		(*d.renderLoopCallback)(dt)
+	}
+	d.mutex.Unlock()
 }
 
 func (d *Drawlib) eventLoop() {
@@ -257,6 +279,7 @@ func (d *Drawlib) eventLoop() {
 		case lifecycle.Event:
 			switch e.To {
 			case lifecycle.StageDead:
+				d.quit <- true
 				if d.closeCallback != nil {
 					(*d.closeCallback)()
 				}
@@ -341,10 +364,10 @@ func (d *Drawlib) eventLoop() {
 					offsetY := (size.Y - d.Canvas.Height()) / 2
 					offsetW := offsetX + d.Canvas.Width()
 					offsetH := offsetY + d.Canvas.Height()
-					d.window.Fill(image.Rect(0, 0, offsetX, size.Y), defaultWindowsBackground, draw.Src)
-					d.window.Fill(image.Rect(offsetW, 0, size.X, size.Y), defaultWindowsBackground, draw.Src)
-					d.window.Fill(image.Rect(0, 0, size.X, offsetY), defaultWindowsBackground, draw.Src)
-					d.window.Fill(image.Rect(0, offsetH, size.X, size.Y), defaultWindowsBackground, draw.Src)
+					d.window.Fill(image.Rect(0, 0, offsetX, size.Y), defaultWindowsBackground)
+					d.window.Fill(image.Rect(offsetW, 0, size.X, size.Y), defaultWindowsBackground)
+					d.window.Fill(image.Rect(0, 0, size.X, offsetY), defaultWindowsBackground)
+					d.window.Fill(image.Rect(0, offsetH, size.X, size.Y), defaultWindowsBackground)
 					d.rect = image.Rect(offsetX, offsetY, offsetW, offsetH)
 
 				} else if size.X < w || size.Y < h {
@@ -352,8 +375,8 @@ func (d *Drawlib) eventLoop() {
 						offsetY := (size.Y-h)/2 + (w-size.X)/2
 						offsetX := offsetY + size.X
 						d.mutex.Lock()
-						d.window.Fill(image.Rect(0, 0, size.X, offsetY), defaultWindowsBackground, draw.Src)
-						d.window.Fill(image.Rect(0, offsetX, size.X, size.Y), defaultWindowsBackground, draw.Src)
+						d.window.Fill(image.Rect(0, 0, size.X, offsetY), defaultWindowsBackground)
+						d.window.Fill(image.Rect(0, offsetX, size.X, size.Y), defaultWindowsBackground)
 						d.rect = image.Rect(0, offsetY, size.X, offsetX)
 						d.mutex.Unlock()
 
@@ -361,8 +384,8 @@ func (d *Drawlib) eventLoop() {
 						offsetX := (size.X-w)/2 + (h-size.Y)/2
 						offsetY := offsetX + size.Y
 						d.mutex.Lock()
-						d.window.Fill(image.Rect(0, 0, offsetX, size.Y), defaultWindowsBackground, draw.Src)
-						d.window.Fill(image.Rect(offsetY, 0, size.X, size.Y), defaultWindowsBackground, draw.Src)
+						d.window.Fill(image.Rect(0, 0, offsetX, size.Y), defaultWindowsBackground)
+						d.window.Fill(image.Rect(offsetY, 0, size.X, size.Y), defaultWindowsBackground)
 						d.rect = image.Rect(offsetX, 0, offsetY, size.Y)
 						d.mutex.Unlock()
 
@@ -374,6 +397,11 @@ func (d *Drawlib) eventLoop() {
 				(*d.sizeCallback)(size.X, size.Y)
 			}
 			d.mutex.Unlock()
+		case stepEvent:
+			d.frame(fixedDelta())
+			d.mutex.Lock()
+			d.swapbuffer()
+			d.mutex.Unlock()
 		case updateEvent:
 			d.mutex.Lock()
 			d.swapbuffer()
@@ -385,10 +413,7 @@ func (d *Drawlib) eventLoop() {
 }
 
 func (d *Drawlib) swapbuffer() {
-	draw.Draw(d.buffer.RGBA(), d.buffer.Bounds(), d.Canvas.im, image.ZP, draw.Src)
-	d.texture.Upload(image.ZP, d.buffer, d.buffer.Bounds())
-	d.window.Scale(d.rect, d.texture, d.texture.Bounds(), draw.Src, nil)
-	d.window.Publish()
+	d.window.Present(d.Canvas.im, d.rect)
 }
 
 func (d *Drawlib) CaptureScreen(path string) {
@@ -404,21 +429,36 @@ func (d *Drawlib) Height() float64 {
 }
 
 func (d *Drawlib) Quit() {
+	if d.headless {
+		select {
+		case d.quit <- true:
+		default:
+		}
+		return
+	}
 	d.window.Send(lifecycle.Event{To: lifecycle.StageDead})
 }
 
 func (d *Drawlib) SetMaximize(maximize bool) {
-	d.window.SetMaximize(maximize)
+	if d.window != nil {
+		d.window.SetMaximize(maximize)
+	}
 }
 
 func (d *Drawlib) SetFullScreen(fullscreen bool) {
-	d.window.SetFullScreen(fullscreen)
+	if d.window != nil {
+		d.window.SetFullScreen(fullscreen)
+	}
 }
 
 func (d *Drawlib) SetSize(width, height int) {
-	d.window.SetDimention(int32(width), int32(height))
+	if d.window != nil {
+		d.window.SetSize(width, height)
+	}
 }
 
 func (d *Drawlib) SetLocation(x, y int) {
-	d.window.MoveWindow(int32(x), int32(y), int32(d.Width()), int32(d.Height()))
+	if d.window != nil {
+		d.window.SetLocation(x, y, d.options.Width, d.options.Height)
+	}
 }
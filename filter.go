@@ -0,0 +1,312 @@
+package drawlib
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+type (
+	// Filter changes an image in place. The pixels are premultiplied by
+	// alpha, as in every image.RGBA.
+	Filter interface {
+		Apply(im *image.RGBA)
+	}
+	blurFilter struct {
+		sigma  float64
+		radius int
+	}
+	convolutionFilter struct {
+		kernel []float64
+		size   int
+	}
+	colorMatrixFilter struct {
+		m [20]float64
+	}
+
+	// boundedFilter is implemented by filters that move color at most
+	// reach pixels and leave transparent pixels further from the shape
+	// transparent, so that shapes can be filtered on layers no larger
+	// than their bounds plus reach. A negative reach means unbounded.
+	boundedFilter interface {
+		reach() int
+	}
+)
+
+// SetFilters sets the filters applied, in order, to everything drawn after
+// it, like CSS filter. Each fill, stroke, text or image is drawn onto its
+// own layer, which is filtered and then drawn onto the canvas. Calling it
+// with no filters turns filtering off.
+func (c *Canvas) SetFilters(filters ...Filter) *Canvas {
+	c.filters = append([]Filter(nil), filters...)
+	return c
+}
+
+// ApplyFilters applies filters, in order, to the part of the canvas inside
+// r. Only the raster image changes; nothing is recorded.
+func (c *Canvas) ApplyFilters(r image.Rectangle, filters ...Filter) *Canvas {
+	r = r.Intersect(c.im.Bounds())
+	if r.Empty() {
+		return c
+	}
+	region := cloneRGBA(c.im.SubImage(r).(*image.RGBA))
+	for _, f := range filters {
+		f.Apply(region)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		copy(c.im.Pix[c.im.PixOffset(r.Min.X, y):c.im.PixOffset(r.Max.X, y)],
+			region.Pix[region.PixOffset(r.Min.X, y):region.PixOffset(r.Max.X, y)])
+	}
+	return c
+}
+
+// filtersReach returns how far filters together spread the pixels of a
+// shape, or false when it is not known.
+func filtersReach(filters []Filter) (int, bool) {
+	reach := 0
+	for _, f := range filters {
+		b, ok := f.(boundedFilter)
+		if !ok || b.reach() < 0 {
+			return 0, false
+		}
+		reach += b.reach()
+	}
+	return reach, true
+}
+
+// ApplyFilters returns a copy of im with filters applied in order.
+func ApplyFilters(im image.Image, filters ...Filter) *image.RGBA {
+	result := ImageToRGBA(im)
+	for _, f := range filters {
+		f.Apply(result)
+	}
+	return result
+}
+
+// NewGaussianBlurFilter blurs with standard deviation sigma, like CSS
+// blur(). Pixels beyond the edges count as transparent.
+func NewGaussianBlurFilter(sigma float64) Filter {
+	return &blurFilter{sigma: sigma}
+}
+
+// NewBoxBlurFilter averages each pixel with its neighbours up to radius
+// pixels away in each direction.
+func NewBoxBlurFilter(radius int) Filter {
+	return &blurFilter{radius: radius}
+}
+
+func (f *blurFilter) Apply(im *image.RGBA) {
+	b := im.Bounds()
+	if f.sigma > 0 {
+		gaussianBlur(im.Pix, b.Dx(), b.Dy(), im.Stride, 4, f.sigma)
+	} else {
+		boxBlur(im.Pix, b.Dx(), b.Dy(), im.Stride, 4, f.radius)
+	}
+}
+
+func (f *blurFilter) reach() int {
+	if f.sigma > 0 {
+		return blurReach(f.sigma)
+	}
+	return f.radius
+}
+
+// NewConvolutionFilter convolves the image with a square kernel given row
+// by row, whose side is odd. Pixels beyond the edges repeat the edge. It
+// returns an error when the kernel is not an odd square or holds NaN or
+// infinite weights.
+func NewConvolutionFilter(kernel []float64) (Filter, error) {
+	size := int(math.Sqrt(float64(len(kernel))))
+	if size*size != len(kernel) || size%2 == 0 {
+		return nil, errors.New("kernel is not an odd square")
+	}
+	if !finite(kernel...) {
+		return nil, errors.New("kernel weight is not finite")
+	}
+	return &convolutionFilter{append([]float64(nil), kernel...), size}, nil
+}
+
+// NewSharpenFilter sharpens by amount; 1 is a common strength.
+func NewSharpenFilter(amount float64) Filter {
+	return &convolutionFilter{[]float64{
+		0, -amount, 0,
+		-amount, 1 + 4*amount, -amount,
+		0, -amount, 0,
+	}, 3}
+}
+
+func NewEmbossFilter() Filter {
+	return &convolutionFilter{[]float64{
+		-2, -1, 0,
+		-1, 1, 1,
+		0, 1, 2,
+	}, 3}
+}
+
+func (f *convolutionFilter) reach() int {
+	return f.size / 2
+}
+
+func (f *convolutionFilter) Apply(im *image.RGBA) {
+	b := im.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := cloneRGBA(im)
+	r := f.size / 2
+	clamp := func(v, n int) int {
+		if v < 0 {
+			return 0
+		}
+		if v >= n {
+			return n - 1
+		}
+		return v
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum [4]float64
+			for ky := 0; ky < f.size; ky++ {
+				row := clamp(y+ky-r, h) * src.Stride
+				for kx := 0; kx < f.size; kx++ {
+					k := f.kernel[ky*f.size+kx]
+					if k == 0 {
+						continue
+					}
+					i := row + clamp(x+kx-r, w)*4
+					for ch := range sum {
+						sum[ch] += k * float64(src.Pix[i+ch])
+					}
+				}
+			}
+			a := math.Max(0, math.Min(255, sum[3]))
+			i := y*im.Stride + x*4
+			for ch := 0; ch < 3; ch++ {
+				im.Pix[i+ch] = uint8(math.Max(0, math.Min(a, sum[ch])) + 0.5)
+			}
+			im.Pix[i+3] = uint8(a + 0.5)
+		}
+	}
+}
+
+// NewColorMatrixFilter transforms the color of each pixel by a 4x5 matrix
+// given row by row, as in SVG feColorMatrix. The matrix maps the column
+// R, G, B, A, 1 of unpremultiplied components between 0 and 1 to the new
+// R, G, B and A.
+func NewColorMatrixFilter(m [20]float64) Filter {
+	return &colorMatrixFilter{m}
+}
+
+// rgbMatrixFilter is a color matrix filter that mixes only R, G and B.
+func rgbMatrixFilter(m [9]float64) Filter {
+	return NewColorMatrixFilter([20]float64{
+		m[0], m[1], m[2], 0, 0,
+		m[3], m[4], m[5], 0, 0,
+		m[6], m[7], m[8], 0, 0,
+		0, 0, 0, 1, 0,
+	})
+}
+
+// linearFilter scales R, G and B by slope and adds intercept.
+func linearFilter(slope, intercept float64) Filter {
+	return NewColorMatrixFilter([20]float64{
+		slope, 0, 0, 0, intercept,
+		0, slope, 0, 0, intercept,
+		0, 0, slope, 0, intercept,
+		0, 0, 0, 1, 0,
+	})
+}
+
+// NewGrayscaleFilter converts to grayscale by amount, from 0 to 1, like
+// CSS grayscale().
+func NewGrayscaleFilter(amount float64) Filter {
+	a := 1 - math.Max(0, math.Min(1, amount))
+	return rgbMatrixFilter([9]float64{
+		0.2126 + 0.7874*a, 0.7152 - 0.7152*a, 0.0722 - 0.0722*a,
+		0.2126 - 0.2126*a, 0.7152 + 0.2848*a, 0.0722 - 0.0722*a,
+		0.2126 - 0.2126*a, 0.7152 - 0.7152*a, 0.0722 + 0.9278*a,
+	})
+}
+
+// NewSepiaFilter converts to sepia by amount, from 0 to 1, like CSS
+// sepia().
+func NewSepiaFilter(amount float64) Filter {
+	a := 1 - math.Max(0, math.Min(1, amount))
+	return rgbMatrixFilter([9]float64{
+		0.393 + 0.607*a, 0.769 - 0.769*a, 0.189 - 0.189*a,
+		0.349 - 0.349*a, 0.686 + 0.314*a, 0.168 - 0.168*a,
+		0.272 - 0.272*a, 0.534 - 0.534*a, 0.131 + 0.869*a,
+	})
+}
+
+// NewSaturateFilter scales the saturation by amount like CSS saturate(); 0
+// is grayscale and values above 1 oversaturate.
+func NewSaturateFilter(amount float64) Filter {
+	s := amount
+	return rgbMatrixFilter([9]float64{
+		0.213 + 0.787*s, 0.715 - 0.715*s, 0.072 - 0.072*s,
+		0.213 - 0.213*s, 0.715 + 0.285*s, 0.072 - 0.072*s,
+		0.213 - 0.213*s, 0.715 - 0.715*s, 0.072 + 0.928*s,
+	})
+}
+
+// NewHueRotateFilter rotates hues by angle radians like CSS hue-rotate().
+func NewHueRotateFilter(angle float64) Filter {
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	return rgbMatrixFilter([9]float64{
+		0.213 + cos*0.787 - sin*0.213, 0.715 - cos*0.715 - sin*0.715, 0.072 - cos*0.072 + sin*0.928,
+		0.213 - cos*0.213 + sin*0.143, 0.715 + cos*0.285 + sin*0.140, 0.072 - cos*0.072 - sin*0.283,
+		0.213 - cos*0.213 - sin*0.787, 0.715 - cos*0.715 + sin*0.715, 0.072 + cos*0.928 + sin*0.072,
+	})
+}
+
+// NewBrightnessFilter scales the colors by amount like CSS brightness().
+func NewBrightnessFilter(amount float64) Filter {
+	return linearFilter(amount, 0)
+}
+
+// NewContrastFilter scales the contrast by amount like CSS contrast(); 1
+// leaves the image unchanged.
+func NewContrastFilter(amount float64) Filter {
+	return linearFilter(amount, 0.5-0.5*amount)
+}
+
+// NewInvertFilter inverts the colors by amount, from 0 to 1, like CSS
+// invert().
+func NewInvertFilter(amount float64) Filter {
+	a := math.Max(0, math.Min(1, amount))
+	return linearFilter(1-2*a, a)
+}
+
+// reach is unbounded when the filter gives transparent pixels alpha.
+func (f *colorMatrixFilter) reach() int {
+	if f.m[19] > 0 {
+		return -1
+	}
+	return 0
+}
+
+func (f *colorMatrixFilter) Apply(im *image.RGBA) {
+	b := im.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			i := y*im.Stride + x*4
+			p := im.Pix[i : i+4]
+			in := [5]float64{0, 0, 0, float64(p[3]) / 255, 1}
+			if p[3] > 0 {
+				for ch := 0; ch < 3; ch++ {
+					in[ch] = float64(p[ch]) / float64(p[3])
+				}
+			}
+			var out [4]float64
+			for row := range out {
+				for col, v := range in {
+					out[row] += f.m[row*5+col] * v
+				}
+				out[row] = math.Max(0, math.Min(1, out[row]))
+			}
+			for ch := 0; ch < 3; ch++ {
+				p[ch] = uint8(out[ch]*out[3]*255 + 0.5)
+			}
+			p[3] = uint8(out[3]*255 + 0.5)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestNewConvolutionFilter(t *testing.T) {
+	for _, kernel := range [][]float64{
+		nil,
+		{1, 2},
+		{1, 1, 1, 1},
+		{0, 0, 0, 0, math.NaN(), 0, 0, 0, 0},
+		{0, 0, 0, 0, math.Inf(1), 0, 0, 0, 0},
+	} {
+		if f, err := NewConvolutionFilter(kernel); err == nil || f != nil {
+			t.Errorf("%v: got %v, %v; want an error", kernel, f, err)
+		}
+	}
+	f, err := NewConvolutionFilter([]float64{0, 0, 0, 0, 1, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	im.SetRGBA(1, 1, color.RGBA{10, 20, 30, 40})
+	before := append([]uint8(nil), im.Pix...)
+	f.Apply(im)
+	for i := range before {
+		if im.Pix[i] != before[i] {
+			t.Fatalf("identity kernel changed the image: %v, want %v", im.Pix, before)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package drawlib
+
+import (
+	"github.com/golang/freetype/raster"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// stringPath returns the outlines of s in device space, laid out like
+// drawString with the baseline starting at x, y. It needs a TrueType font
+// loaded with LoadFontFace.
+func (c *Canvas) stringPath(s string, x, y float64) raster.Path {
+	var path raster.Path
+	if c.font == nil {
+		return path
+	}
+	scale := Fix(c.fontSize)
+	gb := &truetype.GlyphBuf{}
+	prev, hasPrev := truetype.Index(0), false
+	for _, r := range s {
+		index := c.font.Index(r)
+		if hasPrev {
+			x += Unfix(c.font.Kern(scale, prev, index))
+		}
+		if err := gb.Load(c.font, scale, index, font.HintingNone); err != nil {
+			continue
+		}
+		appendGlyph(&path, gb, c.matrix.Translate(x, y))
+		x += Unfix(gb.AdvanceWidth)
+		prev, hasPrev = index, true
+	}
+	return path
+}
+
+// appendGlyph adds the quadratic contours of a loaded glyph, mapping glyph
+// space (y up) through m.
+func appendGlyph(path *raster.Path, gb *truetype.GlyphBuf, m *Matrix) {
+	point := func(p truetype.Point) fixed.Point26_6 {
+		return Fixp(m.TransformPoint(Unfix(p.X), -Unfix(p.Y)))
+	}
+	mid := func(a, b truetype.Point) truetype.Point {
+		return truetype.Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2, Flags: 1}
+	}
+	e0 := 0
+	for _, e1 := range gb.Ends {
+		ps := gb.Points[e0:e1]
+		e0 = e1
+		if len(ps) == 0 {
+			continue
+		}
+		start := ps[0]
+		if start.Flags&1 == 0 {
+			last := ps[len(ps)-1]
+			if last.Flags&1 != 0 {
+				start = last
+			} else {
+				start = mid(last, start)
+			}
+		}
+		path.Start(point(start))
+		var control truetype.Point
+		hasControl := false
+		for i := range ps {
+			p := ps[i]
+			if p.Flags&1 != 0 {
+				if hasControl {
+					path.Add2(point(control), point(p))
+				} else {
+					path.Add1(point(p))
+				}
+				hasControl = false
+				continue
+			}
+			if hasControl {
+				q := mid(control, p)
+				path.Add2(point(control), point(q))
+			}
+			control, hasControl = p, true
+		}
+		if hasControl {
+			path.Add2(point(control), point(start))
+		} else {
+			path.Add1(point(start))
+		}
+	}
+}
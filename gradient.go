@@ -6,28 +6,76 @@ import (
 	"sort"
 )
 
+type Spread int
+
+const (
+	SpreadPad Spread = iota
+	SpreadRepeat
+	SpreadReflect
+)
+
 type (
 	stop struct {
 		pos   float64
 		color color.Color
 	}
-	stops    []stop
+	stops []stop
+	// Gradient is a pattern defined in user space: when it fills or strokes
+	// it follows the canvas matrix at that time, as in HTML canvas.
 	Gradient interface {
 		Pattern
 		AddColorStop(offset float64, color color.Color)
 	}
+	// TransformableGradient is a Gradient whose spread and matrix can be
+	// set. Its matrix maps the gradient's coordinates into user space. The
+	// gradients of this package implement it:
+	//
+	//	g := NewLinearGradient(0, 0, 10, 0)
+	//	g.(TransformableGradient).SetSpread(SpreadRepeat)
+	TransformableGradient interface {
+		Gradient
+		SetSpread(spread Spread)
+		SetMatrix(m *Matrix)
+	}
+	// gradient holds what all gradients share. version counts changes, so
+	// recorders do not reuse a paint server defined before one. sample is
+	// where in a pixel the gradient is sampled: linear gradients have always
+	// used the pixel corner, the others its centre.
+	gradient struct {
+		stops   stops
+		spread  Spread
+		matrix  *Matrix
+		version int
+		sample  float64
+	}
+	// gradientShape is a gradient that maps a point of its own space to a
+	// position along its stops.
+	gradientShape interface {
+		TransformableGradient
+		base() *gradient
+		position(x, y float64) (float64, bool)
+	}
+	// userGradient is a gradient seen through a canvas matrix.
+	userGradient struct {
+		g       gradientShape
+		inverse *Matrix
+	}
 	linearGradient struct {
+		gradient
 		x0, y0, x1, y1 float64
-		stops          stops
 	}
 	circle struct {
 		x, y, r float64
 	}
 	radialGradient struct {
+		gradient
 		c0, c1, cd circle
 		a, inva    float64
 		mindr      float64
-		stops      stops
+	}
+	conicGradient struct {
+		gradient
+		cx, cy, angle float64
 	}
 )
 
@@ -43,37 +91,99 @@ func (s stops) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-func (g *linearGradient) ColorAt(x, y int) color.Color {
-	if len(g.stops) == 0 {
-		return color.Transparent
+func (s stops) opaque() bool {
+	for _, stop := range s {
+		if _, _, _, a := stop.color.RGBA(); a != 0xffff {
+			return false
+		}
 	}
+	return true
+}
 
-	fx, fy := float64(x), float64(y)
-	x0, y0, x1, y1 := g.x0, g.y0, g.x1, g.y1
-	dx, dy := x1-x0, y1-y0
+func (g *gradient) AddColorStop(offset float64, color color.Color) {
+	g.stops = append(g.stops, stop{pos: offset, color: color})
+	sort.Sort(g.stops)
+	g.version++
+}
+
+// SetSpread sets how the gradient continues before offset 0 and after
+// offset 1: padded with the end colors, repeated or reflected.
+func (g *gradient) SetSpread(spread Spread) {
+	g.spread = spread
+	g.version++
+}
 
-	if dy == 0 && dx != 0 {
-		return getColor((fx-x0)/dx, g.stops)
+// SetMatrix sets the transform from the gradient's coordinates to user
+// space, like SVG gradientTransform.
+func (g *gradient) SetMatrix(m *Matrix) {
+	matrix := *m
+	g.matrix = &matrix
+	g.version++
+}
+
+func (g *gradient) base() *gradient {
+	return g
+}
+
+// deviceMatrix returns the transform from the gradient's space to device
+// space when the canvas matrix is m.
+func (g *gradient) deviceMatrix(m *Matrix) *Matrix {
+	if g.matrix == nil {
+		return m
 	}
+	return g.matrix.Multiply(*m)
+}
 
-	if dx == 0 && dy != 0 {
-		return getColor((fy-y0)/dy, g.stops)
+// gradientColor returns the color of g at the device pixel x, y, with
+// inverse mapping device space to the gradient's space.
+func gradientColor(g gradientShape, inverse *Matrix, x, y int) color.Color {
+	b := g.base()
+	if len(b.stops) == 0 {
+		return color.Transparent
+	}
+	fx, fy := inverse.TransformPoint(float64(x)+b.sample, float64(y)+b.sample)
+	t, ok := g.position(fx, fy)
+	if !ok {
+		return color.Transparent
+	}
+	switch b.spread {
+	case SpreadRepeat:
+		t -= math.Floor(t)
+	case SpreadReflect:
+		t = math.Mod(math.Abs(t), 2)
+		if t > 1 {
+			t = 2 - t
+		}
 	}
+	return getColor(t, b.stops)
+}
 
-	s0 := dx*(fx-x0) + dy*(fy-y0)
-	if s0 < 0 {
-		return g.stops[0].color
+// devicePattern returns pattern as sampled in device space by the painters.
+// Gradients follow the current matrix; other patterns are already in
+// device space.
+func (c *Canvas) devicePattern(pattern Pattern) Pattern {
+	if g, ok := pattern.(gradientShape); ok && !isIdentity(c.matrix) {
+		return &userGradient{g, g.base().deviceMatrix(c.matrix).Invert()}
 	}
-	mag := math.Hypot(dx, dy)
-	u := ((fx-x0)*-dy + (fy-y0)*dx) / (mag * mag)
-	x2, y2 := x0+u*-dy, y0+u*dx
-	d := math.Hypot(fx-x2, fy-y2) / mag
-	return getColor(d, g.stops)
+	return pattern
 }
 
-func (g *linearGradient) AddColorStop(offset float64, color color.Color) {
-	g.stops = append(g.stops, stop{pos: offset, color: color})
-	sort.Sort(g.stops)
+func (p *userGradient) ColorAt(x, y int) color.Color {
+	return gradientColor(p.g, p.inverse, x, y)
+}
+
+func (g *linearGradient) ColorAt(x, y int) color.Color {
+	return gradientColor(g, g.deviceMatrix(Identity()).Invert(), x, y)
+}
+
+// position projects the point onto the line through the end points.
+func (g *linearGradient) position(x, y float64) (float64, bool) {
+	dx, dy := g.x1-g.x0, g.y1-g.y0
+	d2 := dx*dx + dy*dy
+	if d2 == 0 {
+		return 0, false
+	}
+	return ((x-g.x0)*dx + (y-g.y0)*dy) / d2, true
 }
 
 func NewLinearGradient(x0, y0, x1, y1 float64) Gradient {
@@ -89,23 +199,22 @@ func dot3(x0, y0, z0, x1, y1, z1 float64) float64 {
 }
 
 func (g *radialGradient) ColorAt(x, y int) color.Color {
-	if len(g.stops) == 0 {
-		return color.Transparent
-	}
+	return gradientColor(g, g.deviceMatrix(Identity()).Invert(), x, y)
+}
 
-	dx, dy := float64(x)+0.5-g.c0.x, float64(y)+0.5-g.c0.y
+// position finds the largest t whose circle, interpolated between the two
+// circles, passes through the point with a radius that is not negative.
+func (g *radialGradient) position(x, y float64) (float64, bool) {
+	dx, dy := x-g.c0.x, y-g.c0.y
 	b := dot3(dx, dy, g.c0.r, g.cd.x, g.cd.y, g.cd.r)
 	c := dot3(dx, dy, -g.c0.r, dx, dy, g.c0.r)
 
 	if g.a == 0 {
 		if b == 0 {
-			return color.Transparent
+			return 0, false
 		}
 		t := 0.5 * c / b
-		if t*g.cd.r >= g.mindr {
-			return getColor(t, g.stops)
-		}
-		return color.Transparent
+		return t, t*g.cd.r >= g.mindr
 	}
 
 	discr := dot3(b, g.a, 0, b, -c, 0)
@@ -115,18 +224,12 @@ func (g *radialGradient) ColorAt(x, y int) color.Color {
 		t1 := (b - sqrtdiscr) * g.inva
 
 		if t0*g.cd.r >= g.mindr {
-			return getColor(t0, g.stops)
+			return t0, true
 		} else if t1*g.cd.r >= g.mindr {
-			return getColor(t1, g.stops)
+			return t1, true
 		}
 	}
-
-	return color.Transparent
-}
-
-func (g *radialGradient) AddColorStop(offset float64, color color.Color) {
-	g.stops = append(g.stops, stop{pos: offset, color: color})
-	sort.Sort(g.stops)
+	return 0, false
 }
 
 func NewRadialGradient(x0, y0, r0, x1, y1, r1 float64) Gradient {
@@ -140,16 +243,37 @@ func NewRadialGradient(x0, y0, r0, x1, y1, r1 float64) Gradient {
 	}
 	mindr := -c0.r
 	g := &radialGradient{
-		c0:    c0,
-		c1:    c1,
-		cd:    cd,
-		a:     a,
-		inva:  inva,
-		mindr: mindr,
+		gradient: gradient{sample: 0.5},
+		c0:       c0,
+		c1:       c1,
+		cd:       cd,
+		a:        a,
+		inva:     inva,
+		mindr:    mindr,
 	}
 	return g
 }
 
+func (g *conicGradient) ColorAt(x, y int) color.Color {
+	return gradientColor(g, g.deviceMatrix(Identity()).Invert(), x, y)
+}
+
+func (g *conicGradient) position(x, y float64) (float64, bool) {
+	t := math.Mod((math.Atan2(y-g.cy, x-g.cx)-g.angle)/(2*math.Pi), 1)
+	if t < 0 {
+		t++
+	}
+	return t, true
+}
+
+// NewConicGradient returns a gradient that sweeps around cx, cy, like HTML
+// canvas createConicGradient. Offset 0 lies in the direction startAngle, in
+// radians from the x axis, and the offsets grow clockwise on screen to 1
+// after a full turn.
+func NewConicGradient(cx, cy, startAngle float64) Gradient {
+	return &conicGradient{gradient: gradient{sample: 0.5}, cx: cx, cy: cy, angle: startAngle}
+}
+
 func getColor(pos float64, stops stops) color.Color {
 	if pos <= 0.0 || len(stops) == 1 {
 		return stops[0].color
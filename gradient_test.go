@@ -0,0 +1,112 @@
+package drawlib
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// grayGradient returns g with stops from black at 0 to white at 1, so the
+// red channel of a pixel reads back its position.
+func grayGradient(g Gradient) Gradient {
+	g.AddColorStop(0, color.Black)
+	g.AddColorStop(1, color.White)
+	return g
+}
+
+// gradientAt fills a canvas with g after setup and returns the position
+// read back at the pixel x, y.
+func gradientAt(g Gradient, setup func(c *Canvas), x, y int) float64 {
+	c := NewCanvas(40, 40)
+	if setup != nil {
+		setup(c)
+	}
+	c.SetFillStyle(g)
+	c.DrawRectangle(-200, -200, 400, 400)
+	c.Fill()
+	return float64(c.im.RGBAAt(x, y).R) / 255
+}
+
+func TestLinearGradientSamplesPixelCorner(t *testing.T) {
+	g := grayGradient(NewLinearGradient(0, 0, 10, 0))
+	for _, test := range []struct {
+		x    int
+		want float64
+	}{
+		{0, 0},
+		{5, 0.5},
+		{10, 1},
+	} {
+		if got := gradientAt(g, nil, test.x, 0); math.Abs(got-test.want) > 0.01 {
+			t.Errorf("pixel %d at %.3f, want %v", test.x, got, test.want)
+		}
+	}
+}
+
+func TestGradientSpread(t *testing.T) {
+	for _, test := range []struct {
+		spread       Spread
+		before, past float64
+	}{
+		{SpreadPad, 0, 1},
+		{SpreadRepeat, 0.7, 0.3},
+		{SpreadReflect, 0.3, 0.7},
+	} {
+		g := grayGradient(NewLinearGradient(10, 0, 20, 0))
+		g.(TransformableGradient).SetSpread(test.spread)
+		if got := gradientAt(g, nil, 7, 0); math.Abs(got-test.before) > 0.01 {
+			t.Errorf("spread %d: before the start at %.3f, want %v", test.spread, got, test.before)
+		}
+		if got := gradientAt(g, nil, 23, 0); math.Abs(got-test.past) > 0.01 {
+			t.Errorf("spread %d: past the end at %.3f, want %v", test.spread, got, test.past)
+		}
+	}
+}
+
+func TestGradientTransforms(t *testing.T) {
+	matrix := grayGradient(NewLinearGradient(0, 0, 10, 0))
+	matrix.(TransformableGradient).SetMatrix(Scale(2, 1))
+	for name, test := range map[string]struct {
+		g     Gradient
+		setup func(c *Canvas)
+		x, y  int
+		want  float64
+	}{
+		"translate":        {grayGradient(NewLinearGradient(0, 0, 10, 0)), func(c *Canvas) { c.Translate(10, 0) }, 15, 0, 0.5},
+		"scale":            {grayGradient(NewLinearGradient(0, 0, 10, 0)), func(c *Canvas) { c.Scale(2, 1) }, 10, 0, 0.5},
+		"rotate":           {grayGradient(NewLinearGradient(0, 0, 10, 0)), func(c *Canvas) { c.Rotate(math.Pi / 2) }, 3, 5, 0.5},
+		"matrix":           {matrix, nil, 10, 0, 0.5},
+		"matrix and scale": {matrix, func(c *Canvas) { c.Scale(2, 1) }, 20, 0, 0.5},
+		// radial gradients are sampled at the pixel centre, 5.5 and 0.5 from
+		// the translated centre
+		"radial": {grayGradient(NewRadialGradient(0, 0, 0, 0, 0, 10)), func(c *Canvas) { c.Translate(20, 20) }, 25, 20, math.Hypot(5.5, 0.5) / 10},
+	} {
+		if got := gradientAt(test.g, test.setup, test.x, test.y); math.Abs(got-test.want) > 0.01 {
+			t.Errorf("%s: pixel %d,%d at %.3f, want %.3f", name, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestConicGradient(t *testing.T) {
+	// the pixels right of, below, left of and above the centre, sampled at
+	// their centres exactly 10 away from it
+	points := [4][2]int{{30, 20}, {20, 30}, {10, 20}, {20, 10}}
+	for name, test := range map[string]struct {
+		start float64
+		setup func(c *Canvas)
+		want  [4]float64
+	}{
+		"start at +x":   {0, nil, [4]float64{0, 0.25, 0.5, 0.75}},
+		"start at +y":   {math.Pi / 2, nil, [4]float64{0.75, 0, 0.25, 0.5}},
+		"start at -x":   {math.Pi, nil, [4]float64{0.5, 0.75, 0, 0.25}},
+		"rotated":       {0, func(c *Canvas) { c.RotateAbout(math.Pi/2, 20.5, 20.5) }, [4]float64{0.75, 0, 0.25, 0.5}},
+		"rotated start": {math.Pi / 2, func(c *Canvas) { c.RotateAbout(math.Pi/2, 20.5, 20.5) }, [4]float64{0.5, 0.75, 0, 0.25}},
+	} {
+		g := grayGradient(NewConicGradient(20.5, 20.5, test.start))
+		for i, p := range points {
+			if got := gradientAt(g, test.setup, p[0], p[1]); math.Abs(got-test.want[i]) > 0.01 {
+				t.Errorf("%s: pixel %d,%d at %.3f, want %v", name, p[0], p[1], got, test.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package drawlib
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+func (d *Drawlib) startHeadless() {
+	d.mutex = &sync.Mutex{}
+	d.rect = image.Rect(0, 0, d.options.Width, d.options.Height)
+
+	if d.initCallback != nil {
+		(*d.initCallback)()
+	}
+	d.mutex.Lock()
+	if d.renderCallback != nil {
+		(*d.renderCallback)()
+	}
+	d.mutex.Unlock()
+
+	for i := 0; d.frames <= 0 || i < d.frames; i++ {
+		select {
+		case <-d.quit:
+			d.stopHeadless()
+			return
+		default:
+		}
+		d.frame(fixedDelta())
+	}
+	d.stopHeadless()
+}
+
+// fixedDelta is the time in seconds between frames of the fixed clock.
+func fixedDelta() float64 {
+	return float64(tickDuration) / float64(time.Second)
+}
+
+func (d *Drawlib) stopHeadless() {
+	if d.closeCallback != nil {
+		(*d.closeCallback)()
+	}
+}
@@ -0,0 +1,66 @@
+package drawlib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeadlessFrames(t *testing.T) {
+	d := New(Option().Dimension(40, 30).Headless(5))
+	var inits, renders, frames, closes int
+	var deltas []float64
+	d.Init(func() { inits++ })
+	d.Render(func() { renders++ })
+	d.RenderLoop(func(dt float64) {
+		frames++
+		deltas = append(deltas, dt)
+	})
+	d.OnWindowsClose(func() { closes++ })
+	d.Start()
+
+	if inits != 1 || renders != 1 || closes != 1 {
+		t.Errorf("init %d, render %d, close %d; want 1 each", inits, renders, closes)
+	}
+	if frames != 5 {
+		t.Errorf("got %d frames, want 5", frames)
+	}
+	want := float64(tickDuration) / float64(time.Second)
+	for i, dt := range deltas {
+		if dt != want {
+			t.Errorf("frame %d: delta %v, want %v", i, dt, want)
+		}
+	}
+	if w, h := d.Canvas.Width(), d.Canvas.Height(); w != 40 || h != 30 {
+		t.Errorf("canvas is %dx%d, want 40x30", w, h)
+	}
+}
+
+func TestHeadlessQuit(t *testing.T) {
+	d := New(Option().Headless(0))
+	var frames, closes int
+	d.RenderLoop(func(float64) {
+		frames++
+		if frames == 3 {
+			d.Quit()
+		}
+	})
+	d.OnWindowsClose(func() { closes++ })
+	d.Start()
+	if frames != 3 {
+		t.Errorf("got %d frames, want 3", frames)
+	}
+	if closes != 1 {
+		t.Errorf("close called %d times, want 1", closes)
+	}
+}
+
+func TestHeadlessQuitBeforeStart(t *testing.T) {
+	d := New(Option().Headless(0))
+	var frames int
+	d.RenderLoop(func(float64) { frames++ })
+	d.Quit()
+	d.Start()
+	if frames != 0 {
+		t.Errorf("got %d frames after an early Quit, want 0", frames)
+	}
+}
@@ -1,78 +1,51 @@
 package drawlib
 
 import (
+	"math"
+
 	"github.com/golang/freetype/raster"
 	"golang.org/x/image/math/fixed"
 )
 
-func flattenPath(p raster.Path) [][]*Vector {
+// dashPath splits paths into dashes, starting offset into the pattern on
+// every path. A dash running through the start of a closed path is kept in
+// one piece so that it is joined there rather than capped twice, and a
+// closed path that no gap falls on stays closed.
+func dashPath(paths [][]*Vector, closed []bool, dashes []float64, offset float64) ([][]*Vector, []bool) {
 	var result [][]*Vector
-	var path []*Vector
-	var cx, cy float64
-	for i := 0; i < len(p); {
-		switch p[i] {
-		case 0:
-			if len(path) > 0 {
-				result = append(result, path)
-				path = nil
-			}
-			x := Unfix(p[i+1])
-			y := Unfix(p[i+2])
-			path = append(path, NewVector(x, y))
-			cx, cy = x, y
-			i += 4
-		case 1:
-			x := Unfix(p[i+1])
-			y := Unfix(p[i+2])
-			path = append(path, NewVector(x, y))
-			cx, cy = x, y
-			i += 4
-		case 2:
-			x1 := Unfix(p[i+1])
-			y1 := Unfix(p[i+2])
-			x2 := Unfix(p[i+3])
-			y2 := Unfix(p[i+4])
-			points := CreateQuadraticBezier(cx, cy, x1, y1, x2, y2)
-			path = append(path, points...)
-			cx, cy = x2, y2
-			i += 6
-		case 3:
-			x1 := Unfix(p[i+1])
-			y1 := Unfix(p[i+2])
-			x2 := Unfix(p[i+3])
-			y2 := Unfix(p[i+4])
-			x3 := Unfix(p[i+5])
-			y3 := Unfix(p[i+6])
-			points := CreateCubicBezier(cx, cy, x1, y1, x2, y2, x3, y3)
-			path = append(path, points...)
-			cx, cy = x3, y3
-			i += 8
-		default:
-			panic("bad path")
-		}
+	var resultClosed []bool
+	if len(dashes) == 0 {
+		return paths, closed
 	}
-	if len(path) > 0 {
-		result = append(result, path)
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes[:len(dashes):len(dashes)], dashes...)
 	}
-	return result
-}
-
-func dashPath(paths [][]*Vector, dashes []float64) [][]*Vector {
-	var result [][]*Vector
-	if len(dashes) == 0 {
-		return paths
+	total := 0.0
+	for _, d := range dashes {
+		total += d
 	}
-	if len(dashes) == 1 {
-		dashes = append(dashes, dashes[0])
+	if total <= 0 {
+		return paths, closed
 	}
-	for _, path := range paths {
+	offset = math.Mod(offset, total)
+	if offset < 0 {
+		offset += total
+	}
+	startIndex := 0
+	for offset >= dashes[startIndex] {
+		offset -= dashes[startIndex]
+		startIndex = (startIndex + 1) % len(dashes)
+	}
+	for i, path := range paths {
 		if len(path) < 2 {
 			continue
 		}
+		first := len(result)
 		previous := path[0]
 		pathIndex := 1
-		dashIndex := 0
-		segmentLength := 0.0
+		dashIndex := startIndex
+		segmentLength := offset
+		broken := false
 		var segment []*Vector
 		segment = append(segment, previous)
 		for pathIndex < len(path) {
@@ -86,12 +59,14 @@ func dashPath(paths [][]*Vector, dashes []float64) [][]*Vector {
 				segment = append(segment, p)
 				if dashIndex%2 == 0 && len(segment) > 1 {
 					result = append(result, segment)
+					resultClosed = append(resultClosed, false)
 				}
 				segment = nil
 				segment = append(segment, p)
 				segmentLength = 0
 				previous = p
 				dashIndex = (dashIndex + 1) % len(dashes)
+				broken = true
 			} else {
 				segment = append(segment, point)
 				previous = point
@@ -101,9 +76,16 @@ func dashPath(paths [][]*Vector, dashes []float64) [][]*Vector {
 		}
 		if dashIndex%2 == 0 && len(segment) > 1 {
 			result = append(result, segment)
+			resultClosed = append(resultClosed, closed[i] && !broken)
+			if startIndex%2 == 0 && len(result)-first > 1 && closed[i] {
+				last := len(result) - 1
+				result[last] = append(result[last], result[first][1:]...)
+				result = append(result[:first], result[first+1:]...)
+				resultClosed = append(resultClosed[:first], resultClosed[first+1:]...)
+			}
 		}
 	}
-	return result
+	return result, resultClosed
 }
 
 func rasterPath(paths [][]*Vector) raster.Path {
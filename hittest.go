@@ -0,0 +1,42 @@
+package drawlib
+
+// IsPointInPath reports whether x, y lies inside the current path under the
+// current fill rule. x, y is in device space, the pixel coordinates of the
+// canvas, so window coordinates such as mouse positions can be tested
+// directly whatever the current matrix is.
+func (c *Canvas) IsPointInPath(x, y float64) bool {
+	lines, _ := c.path.flatten(c.tolerance)
+	w := windingNumber(lines, x, y)
+	if c.fillRule == FillRuleEvenOdd {
+		return w%2 != 0
+	}
+	return w != 0
+}
+
+// IsPointInStroke reports whether x, y lies inside the outline Stroke would
+// paint for the current path, honoring the line width, cap, join and
+// dashes. x, y is in device space as in IsPointInPath.
+func (c *Canvas) IsPointInStroke(x, y float64) bool {
+	lines, _ := pathFromRaster(c.strokeOutline()).flatten(c.tolerance)
+	return windingNumber(lines, x, y) != 0
+}
+
+// windingNumber returns how many times the polylines, each implicitly
+// closed, wind around x, y.
+func windingNumber(paths [][]*Vector, x, y float64) int {
+	w := 0
+	for _, path := range paths {
+		for i, a := range path {
+			b := path[(i+1)%len(path)]
+			cross := (b.X-a.X)*(y-a.Y) - (x-a.X)*(b.Y-a.Y)
+			if a.Y <= y {
+				if b.Y > y && cross > 0 {
+					w++
+				}
+			} else if b.Y <= y && cross < 0 {
+				w--
+			}
+		}
+	}
+	return w
+}
@@ -0,0 +1,59 @@
+package drawlib
+
+import "testing"
+
+func TestIsPointInPath(t *testing.T) {
+	c := NewCanvas(100, 100)
+	c.Scale(2, 2)
+	c.DrawRectangle(10, 10, 20, 20)
+	c.DrawRectangle(15, 15, 10, 10)
+	for _, test := range []struct {
+		x, y             float64
+		winding, evenOdd bool
+	}{
+		{25, 25, true, true},
+		{40, 40, true, false},
+		{15, 15, false, false},
+		{61, 30, false, false},
+	} {
+		c.SetFillRule(FillRuleWinding)
+		if got := c.IsPointInPath(test.x, test.y); got != test.winding {
+			t.Errorf("winding %v,%v: got %v, want %v", test.x, test.y, got, test.winding)
+		}
+		c.SetFillRule(FillRuleEvenOdd)
+		if got := c.IsPointInPath(test.x, test.y); got != test.evenOdd {
+			t.Errorf("even-odd %v,%v: got %v, want %v", test.x, test.y, got, test.evenOdd)
+		}
+	}
+}
+
+func TestIsPointInStroke(t *testing.T) {
+	c := NewCanvas(100, 100)
+	c.Translate(10, 0)
+	c.SetLineWidth(4)
+	c.SetLineCap(LineCapButt)
+	c.DrawLine(10, 50, 60, 50)
+	for _, test := range []struct {
+		x, y float64
+		want bool
+	}{
+		{40, 50, true},
+		{40, 51.9, true},
+		{40, 48.1, true},
+		{40, 52.1, false},
+		{40, 47.9, false},
+		{19.9, 50, false},
+		{20.1, 50, true},
+		{69.9, 50, true},
+		{70.1, 50, false},
+	} {
+		if got := c.IsPointInStroke(test.x, test.y); got != test.want {
+			t.Errorf("%v,%v: got %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+
+	c.SetDash(10, 10)
+	if !c.IsPointInStroke(25, 50) || c.IsPointInStroke(35, 50) {
+		t.Error("dashes not honored")
+	}
+}
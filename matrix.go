@@ -86,3 +86,23 @@ func (m Matrix) Rotate(angle float64) *Matrix {
 func (m Matrix) Shear(x, y float64) *Matrix {
 	return Shear(x, y).Multiply(m)
 }
+
+func (m Matrix) Invert() *Matrix {
+	det := m.XX*m.YY - m.XY*m.YX
+	if det == 0 {
+		return Identity()
+	}
+	return &Matrix{
+		m.YY / det, -m.YX / det,
+		-m.XY / det, m.XX / det,
+		(m.XY*m.Y0 - m.YY*m.X0) / det,
+		(m.YX*m.X0 - m.XX*m.Y0) / det,
+	}
+}
+
+// maxScale returns the most m stretches any vector.
+func (m Matrix) maxScale() float64 {
+	a := m.XX*m.XX + m.XY*m.XY + m.YX*m.YX + m.YY*m.YY
+	det := m.XX*m.YY - m.XY*m.YX
+	return math.Sqrt((a + math.Sqrt(math.Max(a*a-4*det*det, 0))) / 2)
+}
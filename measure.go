@@ -0,0 +1,125 @@
+package drawlib
+
+import (
+	"math"
+	"sort"
+)
+
+// PathMeasure answers questions about distances along a flattened Path.
+// Distances run through the subpaths in order, as if they were joined.
+type PathMeasure struct {
+	contours [][]*Vector
+	lengths  [][]float64
+	closed   []bool
+	length   float64
+}
+
+func NewPathMeasure(p *Path) *PathMeasure {
+	m := &PathMeasure{}
+	lines, closed := p.flatten(p.flatteningTolerance())
+	for i, line := range lines {
+		contour := []*Vector{line[0]}
+		lengths := []float64{0}
+		for _, v := range line[1:] {
+			d := contour[len(contour)-1].Distance(v)
+			if d == 0 {
+				continue
+			}
+			contour = append(contour, v)
+			lengths = append(lengths, lengths[len(lengths)-1]+d)
+		}
+		if len(contour) < 2 {
+			continue
+		}
+		m.contours = append(m.contours, contour)
+		m.lengths = append(m.lengths, lengths)
+		m.closed = append(m.closed, closed[i])
+		m.length += lengths[len(lengths)-1]
+	}
+	return m
+}
+
+// Length returns the length of p. It is a shorthand for
+// NewPathMeasure(p).Length().
+func (p *Path) Length() float64 {
+	return NewPathMeasure(p).Length()
+}
+
+func (m *PathMeasure) Length() float64 {
+	return m.length
+}
+
+// PointAt returns the point at distance d along the path and the angle in
+// radians of the path's direction there. d is clamped to the path.
+func (m *PathMeasure) PointAt(d float64) (x, y, angle float64) {
+	if len(m.contours) == 0 {
+		return 0, 0, 0
+	}
+	i, d := m.contour(d)
+	contour, lengths := m.contours[i], m.lengths[i]
+	j := segmentAt(lengths, d)
+	a, b := contour[j], contour[j+1]
+	t := (d - lengths[j]) / (lengths[j+1] - lengths[j])
+	p := a.Interpolate(b, t)
+	return p.X, p.Y, math.Atan2(b.Y-a.Y, b.X-a.X)
+}
+
+// SubPath returns the part of the path between distances d0 and d1. A
+// closed subpath that is taken whole stays closed.
+func (m *PathMeasure) SubPath(d0, d1 float64) *Path {
+	result := NewPath()
+	d0, d1 = math.Max(d0, 0), math.Min(d1, m.length)
+	offset := 0.0
+	for i, contour := range m.contours {
+		lengths := m.lengths[i]
+		l := lengths[len(lengths)-1]
+		s, e := d0-offset, d1-offset
+		offset += l
+		if e <= 0 || s >= l || s >= e {
+			continue
+		}
+		s, e = math.Max(s, 0), math.Min(e, l)
+		j, k := segmentAt(lengths, s), segmentAt(lengths, e)
+		start := contour[j].Interpolate(contour[j+1], (s-lengths[j])/(lengths[j+1]-lengths[j]))
+		end := contour[k].Interpolate(contour[k+1], (e-lengths[k])/(lengths[k+1]-lengths[k]))
+		result.MoveTo(start.X, start.Y)
+		for _, v := range contour[j+1 : k+1] {
+			if v.X != start.X || v.Y != start.Y {
+				result.LineTo(v.X, v.Y)
+			}
+		}
+		if s == 0 && e == l && m.closed[i] {
+			result.ClosePath()
+		} else {
+			result.LineTo(end.X, end.Y)
+		}
+	}
+	return result
+}
+
+// contour returns the index of the contour at distance d and the distance
+// into that contour.
+func (m *PathMeasure) contour(d float64) (int, float64) {
+	d = math.Max(0, math.Min(d, m.length))
+	for i, lengths := range m.lengths {
+		l := lengths[len(lengths)-1]
+		if d <= l || i == len(m.lengths)-1 {
+			return i, math.Min(d, l)
+		}
+		d -= l
+	}
+	return 0, 0
+}
+
+// segmentAt returns the index of the segment of a contour with cumulative
+// lengths that contains distance d.
+func segmentAt(lengths []float64, d float64) int {
+	j := sort.SearchFloat64s(lengths, d) - 1
+	if j < 0 {
+		j = 0
+	}
+	if j > len(lengths)-2 {
+		j = len(lengths) - 2
+	}
+	return j
+}
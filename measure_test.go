@@ -0,0 +1,85 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+// measuredPath is an open L of length 70 followed by a closed 10x10
+// square of length 40.
+func measuredPath() *Path {
+	return NewPath().MoveTo(0, 0).LineTo(30, 0).LineTo(30, 40).DrawRectangle(100, 0, 10, 10)
+}
+
+func TestPathMeasureLength(t *testing.T) {
+	if got := measuredPath().Length(); math.Abs(got-110) > 1e-9 {
+		t.Errorf("polyline length %v, want 110", got)
+	}
+	if got, want := NewPath().SetTolerance(0.001).DrawCircle(0, 0, 10).Length(), 20*math.Pi; math.Abs(got-want) > 0.01 {
+		t.Errorf("circle length %v, want %v", got, want)
+	}
+	if got := NewPathMeasure(NewPath()).Length(); got != 0 {
+		t.Errorf("empty path length %v", got)
+	}
+}
+
+func TestPathMeasurePointAt(t *testing.T) {
+	m := NewPathMeasure(measuredPath())
+	for _, test := range []struct {
+		d, x, y, angle float64
+	}{
+		{-5, 0, 0, 0},
+		{15, 15, 0, 0},
+		{50, 30, 20, math.Pi / 2},
+		{70, 30, 40, math.Pi / 2},
+		{85, 110, 5, math.Pi / 2},
+		{105, 100, 5, -math.Pi / 2},
+		{200, 100, 0, -math.Pi / 2},
+	} {
+		x, y, angle := m.PointAt(test.d)
+		if math.Abs(x-test.x) > 1e-9 || math.Abs(y-test.y) > 1e-9 || math.Abs(angle-test.angle) > 1e-9 {
+			t.Errorf("at %v: %v,%v angle %v, want %v,%v angle %v", test.d, x, y, angle, test.x, test.y, test.angle)
+		}
+	}
+
+	circle := NewPathMeasure(NewPath().SetTolerance(0.001).DrawCircle(0, 0, 10))
+	x, y, angle := circle.PointAt(circle.Length() / 4)
+	if math.Abs(x) > 0.01 || math.Abs(y-10) > 0.01 || math.Abs(math.Abs(angle)-math.Pi) > 0.02 {
+		t.Errorf("quarter way round the circle: %v,%v angle %v, want 0,10 angle pi", x, y, angle)
+	}
+}
+
+func TestPathMeasureSubPath(t *testing.T) {
+	m := NewPathMeasure(measuredPath())
+	for _, test := range []struct {
+		d0, d1 float64
+		want   [][]Vector
+		closed []bool
+	}{
+		{20, 50, [][]Vector{{{20, 0}, {30, 0}, {30, 20}}}, []bool{false}},
+		{60, 85, [][]Vector{{{30, 30}, {30, 40}}, {{100, 0}, {110, 0}, {110, 5}}}, []bool{false, false}},
+		{70, 110, [][]Vector{{{100, 0}, {110, 0}, {110, 10}, {100, 10}, {100, 0}}}, []bool{true}},
+		{-10, 1000, [][]Vector{{{0, 0}, {30, 0}, {30, 40}}, {{100, 0}, {110, 0}, {110, 10}, {100, 10}, {100, 0}}}, []bool{false, true}},
+		{50, 40, nil, nil},
+	} {
+		lines, closed := m.SubPath(test.d0, test.d1).flatten(defaultTolerance)
+		if len(lines) != len(test.want) {
+			t.Errorf("%v..%v: %d subpaths, want %d", test.d0, test.d1, len(lines), len(test.want))
+			continue
+		}
+		for i, line := range lines {
+			if closed[i] != test.closed[i] {
+				t.Errorf("%v..%v: subpath %d closed %v, want %v", test.d0, test.d1, i, closed[i], test.closed[i])
+			}
+			if len(line) != len(test.want[i]) {
+				t.Errorf("%v..%v: subpath %d has %d points, want %v", test.d0, test.d1, i, len(line), test.want[i])
+				continue
+			}
+			for j, v := range line {
+				if v.Distance(&test.want[i][j]) > 1e-9 {
+					t.Errorf("%v..%v: subpath %d point %d is %v, want %v", test.d0, test.d1, i, j, *v, test.want[i][j])
+				}
+			}
+		}
+	}
+}
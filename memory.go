@@ -0,0 +1,171 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/mobile/event/size"
+)
+
+type (
+	// MemoryBackend is a Backend without a display. Events passed to Inject
+	// are delivered to the event loop in order. It has no wall clock: frames
+	// run only when stepped by Step, on the fixed clock of headless mode, and
+	// every presented frame is kept as a copy of the whole window surface.
+	MemoryBackend struct {
+		mutex     sync.Mutex
+		cond      *sync.Cond
+		events    []interface{}
+		surface   *image.RGBA
+		frames    []*image.RGBA
+		maxFrames int
+		options   WindowOptions
+	}
+	// stepEvent runs one frame of the event loop on the fixed clock.
+	stepEvent    struct{}
+	memoryWindow struct {
+		b *MemoryBackend
+	}
+)
+
+func NewMemoryBackend() *MemoryBackend {
+	b := &MemoryBackend{}
+	b.cond = sync.NewCond(&b.mutex)
+	return b
+}
+
+func (b *MemoryBackend) Main(o WindowOptions, f func(Window)) error {
+	b.mutex.Lock()
+	b.options = o
+	b.surface = image.NewRGBA(image.Rect(0, 0, o.Width, o.Height))
+	b.events = append([]interface{}{size.Event{WidthPx: o.Width, HeightPx: o.Height}}, b.events...)
+	b.mutex.Unlock()
+	f(&memoryWindow{b})
+	return nil
+}
+
+func (b *MemoryBackend) Inject(event interface{}) {
+	b.mutex.Lock()
+	b.events = append(b.events, event)
+	b.mutex.Unlock()
+	b.cond.Signal()
+}
+
+// Step queues n frames after the events injected so far. Each runs the
+// per-frame callbacks with a delta of one tick and presents the canvas.
+func (b *MemoryBackend) Step(n int) {
+	for i := 0; i < n; i++ {
+		b.Inject(stepEvent{})
+	}
+}
+
+// SetFrameLimit keeps only the last n presented frames; 0 keeps them all.
+func (b *MemoryBackend) SetFrameLimit(n int) {
+	b.mutex.Lock()
+	b.maxFrames = n
+	b.trimFrames()
+	b.mutex.Unlock()
+}
+
+func (b *MemoryBackend) trimFrames() {
+	if b.maxFrames > 0 && len(b.frames) > b.maxFrames {
+		b.frames = append([]*image.RGBA(nil), b.frames[len(b.frames)-b.maxFrames:]...)
+	}
+}
+
+// TakeFrames returns the presented frames and forgets them.
+func (b *MemoryBackend) TakeFrames() []*image.RGBA {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	frames := b.frames
+	b.frames = nil
+	return frames
+}
+
+func (b *MemoryBackend) Frames() []*image.RGBA {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	frames := make([]*image.RGBA, len(b.frames))
+	copy(frames, b.frames)
+	return frames
+}
+
+func (b *MemoryBackend) Surface() *image.RGBA {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return cloneRGBA(b.surface)
+}
+
+// steppedClock reports that frames run only when stepped by Step.
+func (b *MemoryBackend) steppedClock() bool {
+	return true
+}
+
+func (b *MemoryBackend) Options() WindowOptions {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.options
+}
+
+func (w *memoryWindow) NextEvent() interface{} {
+	b := w.b
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for len(b.events) == 0 {
+		b.cond.Wait()
+	}
+	e := b.events[0]
+	b.events = b.events[1:]
+	if e, ok := e.(size.Event); ok {
+		surface := image.NewRGBA(image.Rect(0, 0, e.WidthPx, e.HeightPx))
+		draw.Draw(surface, surface.Bounds(), b.surface, image.ZP, draw.Src)
+		b.surface = surface
+		b.options.Width, b.options.Height = e.WidthPx, e.HeightPx
+	}
+	return e
+}
+
+func (w *memoryWindow) Send(event interface{}) {
+	w.b.Inject(event)
+}
+
+func (w *memoryWindow) Fill(dr image.Rectangle, c color.Color) {
+	w.b.mutex.Lock()
+	draw.Draw(w.b.surface, dr, image.NewUniform(c), image.ZP, draw.Src)
+	w.b.mutex.Unlock()
+}
+
+func (w *memoryWindow) Present(src *image.RGBA, dr image.Rectangle) {
+	b := w.b
+	b.mutex.Lock()
+	if dr.Size() == src.Bounds().Size() {
+		draw.Draw(b.surface, dr, src, src.Bounds().Min, draw.Src)
+	} else {
+		draw.BiLinear.Scale(b.surface, dr, src, src.Bounds(), draw.Src, nil)
+	}
+	b.frames = append(b.frames, cloneRGBA(b.surface))
+	b.trimFrames()
+	b.mutex.Unlock()
+}
+
+func (w *memoryWindow) SetMaximize(maximize bool) {}
+
+func (w *memoryWindow) SetFullScreen(fullscreen bool) {}
+
+func (w *memoryWindow) SetSize(width, height int) {
+	w.Send(size.Event{WidthPx: width, HeightPx: height})
+}
+
+func (w *memoryWindow) SetLocation(x, y, width, height int) {
+	w.b.mutex.Lock()
+	w.b.options.X, w.b.options.Y = x, y
+	resize := width != w.b.options.Width || height != w.b.options.Height
+	w.b.mutex.Unlock()
+	if resize {
+		w.SetSize(width, height)
+	}
+}
+
+func (w *memoryWindow) Release() {}
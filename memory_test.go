@@ -0,0 +1,184 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"golang.org/x/mobile/event/key"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/mouse"
+	"golang.org/x/mobile/event/size"
+)
+
+var red = color.RGBA{255, 0, 0, 255}
+
+func runMemory(b *MemoryBackend, w, h int, setup func(d *Drawlib)) *Drawlib {
+	d := New(Option().Dimension(w, h).Backend(b))
+	d.Render(func() { d.Canvas.Background(255, 0, 0) })
+	if setup != nil {
+		setup(d)
+	}
+	b.Inject(lifecycle.Event{To: lifecycle.StageDead})
+	d.Start()
+	return d
+}
+
+func TestMemoryStep(t *testing.T) {
+	b := NewMemoryBackend()
+	b.Step(3)
+	var frames int
+	runMemory(b, 20, 10, func(d *Drawlib) {
+		d.RenderLoop(func(dt float64) {
+			frames++
+			if dt != fixedDelta() {
+				t.Errorf("delta %v, want %v", dt, fixedDelta())
+			}
+		})
+	})
+	if frames != 3 {
+		t.Errorf("got %d frames, want 3", frames)
+	}
+	if n := len(b.TakeFrames()); n != 3 {
+		t.Errorf("presented %d frames, want 3", n)
+	}
+	if n := len(b.Frames()); n != 0 {
+		t.Errorf("%d frames left after TakeFrames", n)
+	}
+}
+
+// wallClockBackend is a MemoryBackend that keeps the wall clock.
+type wallClockBackend struct {
+	*MemoryBackend
+}
+
+func (b wallClockBackend) steppedClock() bool {
+	return false
+}
+
+func TestMemoryWallClockOptIn(t *testing.T) {
+	b := NewMemoryBackend()
+	d := New(Option().Dimension(20, 10).Backend(wallClockBackend{b}))
+	frames := 0
+	d.RenderLoop(func(dt float64) {
+		if frames++; frames == 2 {
+			b.Inject(lifecycle.Event{To: lifecycle.StageDead})
+		}
+	})
+	timeout := time.AfterFunc(5*time.Second, func() {
+		b.Inject(lifecycle.Event{To: lifecycle.StageDead})
+	})
+	d.Start()
+	timeout.Stop()
+	if frames < 2 {
+		t.Errorf("got %d frames on the wall clock, want 2", frames)
+	}
+}
+
+func TestMemoryFrameLimit(t *testing.T) {
+	b := NewMemoryBackend()
+	b.SetFrameLimit(2)
+	b.Step(5)
+	runMemory(b, 20, 10, nil)
+	if n := len(b.Frames()); n != 2 {
+		t.Errorf("kept %d frames, want 2", n)
+	}
+}
+
+func TestMemoryLetterbox(t *testing.T) {
+	for _, test := range []struct {
+		w, h   int
+		canvas image.Point
+		border image.Point
+	}{
+		{200, 100, image.Pt(100, 50), image.Pt(10, 50)},
+		{50, 100, image.Pt(25, 50), image.Pt(25, 10)},
+	} {
+		b := NewMemoryBackend()
+		b.Inject(size.Event{WidthPx: test.w, HeightPx: test.h})
+		b.Step(1)
+		var sizes []image.Point
+		runMemory(b, 100, 100, func(d *Drawlib) {
+			d.OnSizeChange(func(w, h int) { sizes = append(sizes, image.Pt(w, h)) })
+		})
+		surface := b.Surface()
+		if got := surface.Bounds().Size(); got != image.Pt(test.w, test.h) {
+			t.Errorf("surface is %v, want %dx%d", got, test.w, test.h)
+		}
+		if got := surface.RGBAAt(test.canvas.X, test.canvas.Y); got != red {
+			t.Errorf("%dx%d: canvas pixel %v, want %v", test.w, test.h, got, red)
+		}
+		if got := surface.RGBAAt(test.border.X, test.border.Y); got != defaultWindowsBackground {
+			t.Errorf("%dx%d: border pixel %v, want %v", test.w, test.h, got, defaultWindowsBackground)
+		}
+		if len(sizes) != 2 || sizes[1] != image.Pt(test.w, test.h) {
+			t.Errorf("size callbacks %v", sizes)
+		}
+	}
+}
+
+func TestMemoryAutoScale(t *testing.T) {
+	b := NewMemoryBackend()
+	b.Inject(size.Event{WidthPx: 200, HeightPx: 100})
+	b.Step(1)
+	runMemory(b, 100, 100, func(d *Drawlib) { d.SetAutoScale(true) })
+	surface := b.Surface()
+	for _, p := range []image.Point{{1, 1}, {198, 98}} {
+		if got := surface.RGBAAt(p.X, p.Y); got != red {
+			t.Errorf("pixel %v is %v, want %v", p, got, red)
+		}
+	}
+}
+
+func TestMemoryCallbacks(t *testing.T) {
+	b := NewMemoryBackend()
+	b.Inject(key.Event{Code: key.CodeA, Direction: key.DirPress})
+	b.Inject(mouse.Event{X: 5, Y: 6, Button: mouse.ButtonLeft, Direction: mouse.DirPress})
+	b.Step(2)
+	b.Inject(mouse.Event{X: 7, Y: 8, Button: mouse.ButtonLeft, Direction: mouse.DirRelease})
+	b.Inject(key.Event{Code: key.CodeA, Direction: key.DirRelease})
+	b.Step(1)
+	var presses, keyHeld, mouseHeld, releases, closes int
+	runMemory(b, 20, 10, func(d *Drawlib) {
+		d.OnKeyPress(func(c key.Code) {
+			if c == key.CodeA {
+				presses++
+			}
+		})
+		d.OnKeyIsPress(func(key.Code) { keyHeld++ })
+		d.OnMousePress(func(btn mouse.Button, x, y int) {
+			if x != 5 || y != 6 {
+				t.Errorf("mouse press at %d,%d, want 5,6", x, y)
+			}
+			presses++
+		})
+		d.OnMouseIsPress(func(mouse.Button, int, int) { mouseHeld++ })
+		d.OnMouseRelease(func(btn mouse.Button, x, y int) {
+			if x != 7 || y != 8 {
+				t.Errorf("mouse release at %d,%d, want 7,8", x, y)
+			}
+			releases++
+		})
+		d.OnWindowsClose(func() { closes++ })
+	})
+	if presses != 2 || releases != 1 || closes != 1 {
+		t.Errorf("presses %d, releases %d, closes %d; want 2, 1, 1", presses, releases, closes)
+	}
+	if keyHeld != 2 || mouseHeld != 2 {
+		t.Errorf("key held for %d frames and mouse for %d, want 2 each", keyHeld, mouseHeld)
+	}
+}
+
+func TestMemoryEscape(t *testing.T) {
+	b := NewMemoryBackend()
+	b.Inject(key.Event{Code: key.CodeEscape, Direction: key.DirPress})
+	b.Step(1)
+	d := New(Option().Backend(b))
+	var frames int
+	d.RenderLoop(func(float64) { frames++ })
+	d.Start()
+	if frames != 0 {
+		t.Errorf("ran %d frames after Escape, want 0", frames)
+	}
+}
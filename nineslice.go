@@ -0,0 +1,196 @@
+package drawlib
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+)
+
+type SliceMode int
+
+const (
+	SliceStretch SliceMode = iota
+	SliceTile
+)
+
+type (
+	// Insets are the widths of the borders of a nine-slice image, in pixels.
+	Insets struct {
+		Top, Right, Bottom, Left int
+	}
+
+	// NinePatch is an image parsed from an Android .9.png. Insets marks the
+	// fixed borders around the stretchable area and Padding the borders
+	// around the content area.
+	NinePatch struct {
+		Image   image.Image
+		Insets  Insets
+		Padding Insets
+	}
+)
+
+// SetNineSliceMode sets whether DrawNineSlice stretches or tiles the edges
+// and the center. Both stretch by default.
+func (c *Canvas) SetNineSliceMode(edges, center SliceMode) *Canvas {
+	c.sliceEdges, c.sliceCenter = edges, center
+	return c
+}
+
+// DrawNineSlice draws im into the rectangle at x, y with width w and height
+// h. The corners given by insets keep their size, the edges stretch or tile
+// along their length and the center fills the rest. When the rectangle is
+// smaller than the borders the corners shrink to fit. The pieces cast one
+// shadow and are filtered as one image.
+func (c *Canvas) DrawNineSlice(im image.Image, insets Insets, x, y, w, h float64) *Canvas {
+	c.drawPieces(transformedBounds(c.matrix, x, y, x+w, y+h), func() {
+		c.drawNineSlice(im, insets, x, y, w, h)
+	})
+	return c
+}
+
+func (c *Canvas) drawNineSlice(im image.Image, insets Insets, x, y, w, h float64) {
+	b := im.Bounds()
+	sx := []int{b.Min.X, b.Min.X + insets.Left, b.Max.X - insets.Right, b.Max.X}
+	sy := []int{b.Min.Y, b.Min.Y + insets.Top, b.Max.Y - insets.Bottom, b.Max.Y}
+	fx := math.Min(1, w/float64(insets.Left+insets.Right))
+	fy := math.Min(1, h/float64(insets.Top+insets.Bottom))
+	dx := []float64{x, x + float64(insets.Left)*fx, x + w - float64(insets.Right)*fx, x + w}
+	dy := []float64{y, y + float64(insets.Top)*fy, y + h - float64(insets.Bottom)*fy, y + h}
+	for j := 0; j < 3; j++ {
+		for i := 0; i < 3; i++ {
+			src := image.Rect(sx[i], sy[j], sx[i+1], sy[j+1])
+			if src.Empty() || dx[i+1] <= dx[i] || dy[j+1] <= dy[j] {
+				continue
+			}
+			mode := c.sliceEdges
+			if i == 1 && j == 1 {
+				mode = c.sliceCenter
+			}
+			tile := mode == SliceTile
+			c.drawSlice(im, src, dx[i], dy[j], dx[i+1]-dx[i], dy[j+1]-dy[j], tile && i == 1, tile && j == 1)
+		}
+	}
+}
+
+// DrawNinePatch draws a parsed .9.png like DrawNineSlice.
+func (c *Canvas) DrawNinePatch(p *NinePatch, x, y, w, h float64) *Canvas {
+	return c.DrawNineSlice(p.Image, p.Insets, x, y, w, h)
+}
+
+// drawSlice draws src into the rectangle, repeating it at its own size
+// along the axes that tile and stretching it along the others. Tiles that
+// do not fit are clipped, not rescaled.
+func (c *Canvas) drawSlice(im image.Image, src image.Rectangle, x, y, w, h float64, tileX, tileY bool) {
+	tw, th := w, h
+	if tileX {
+		tw = float64(src.Dx())
+	}
+	if tileY {
+		th = float64(src.Dy())
+	}
+	for ty := 0.0; ty < h; ty += th {
+		for tx := 0.0; tx < w; tx += tw {
+			pw, ph := math.Min(tw, w-tx), math.Min(th, h-ty)
+			if pw == tw && ph == th {
+				c.DrawImageRect(im, src, x+tx, y+ty, pw, ph)
+				continue
+			}
+			fx, fy := 1.0, 1.0
+			if pw < tw {
+				fx = pw / tw
+			}
+			if ph < th {
+				fy = ph / th
+			}
+			tile := clipTile(im, src, fx, fy)
+			b := tile.Bounds()
+			c.DrawImageRect(tile, b, x+tx, y+ty, tw*float64(b.Dx())/float64(src.Dx()), th*float64(b.Dy())/float64(src.Dy()))
+		}
+	}
+}
+
+// clipTile copies the leading fractions fx, fy of src, fading the last
+// partly covered column and row by how much of them is covered.
+func clipTile(im image.Image, src image.Rectangle, fx, fy float64) *image.RGBA {
+	cw, ch := fx*float64(src.Dx()), fy*float64(src.Dy())
+	r := image.Rect(0, 0, int(math.Ceil(cw)), int(math.Ceil(ch)))
+	tile := image.NewRGBA(r)
+	draw.Draw(tile, r, im, src.Min, draw.Src)
+	fade := func(i int, a float64) {
+		p := tile.Pix[i : i+4]
+		for k := range p {
+			p[k] = uint8(float64(p[k])*a + 0.5)
+		}
+	}
+	if a := cw - float64(r.Max.X-1); a < 1 {
+		for y := 0; y < r.Max.Y; y++ {
+			fade(tile.PixOffset(r.Max.X-1, y), a)
+		}
+	}
+	if a := ch - float64(r.Max.Y-1); a < 1 {
+		for x := 0; x < r.Max.X; x++ {
+			fade(tile.PixOffset(x, r.Max.Y-1), a)
+		}
+	}
+	return tile
+}
+
+// LoadNinePatch loads an Android .9.png file.
+func LoadNinePatch(path string) (*NinePatch, error) {
+	im, err := LoadPNG(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseNinePatch(im)
+}
+
+// ParseNinePatch reads the one pixel marker border of an Android .9.png.
+// Black pixels in the top row and left column mark the stretchable area,
+// and those in the bottom row and right column the content area. When a
+// side has several stretchable runs they are taken as one, from the first
+// to the last. A missing content marker means the content area is the
+// stretchable area.
+func ParseNinePatch(im image.Image) (*NinePatch, error) {
+	b := im.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return nil, errors.New("nine-patch image is too small")
+	}
+	inner := image.Rect(b.Min.X+1, b.Min.Y+1, b.Max.X-1, b.Max.Y-1)
+	marked := func(x, y int) bool {
+		r, g, bl, a := im.At(x, y).RGBA()
+		return a == 0xffff && r == 0 && g == 0 && bl == 0
+	}
+	// run returns the first and last marked positions of a border line,
+	// relative to the inner image
+	run := func(n int, at func(i int) bool) (first, last int, ok bool) {
+		first = -1
+		for i := 0; i < n; i++ {
+			if at(i) {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		return first, last, first >= 0
+	}
+	w, h := inner.Dx(), inner.Dy()
+	x0, x1, okX := run(w, func(i int) bool { return marked(inner.Min.X+i, b.Min.Y) })
+	y0, y1, okY := run(h, func(i int) bool { return marked(b.Min.X, inner.Min.Y+i) })
+	if !okX || !okY {
+		return nil, errors.New("nine-patch image has no stretch markers")
+	}
+	p := &NinePatch{Insets: Insets{Top: y0, Right: w - 1 - x1, Bottom: h - 1 - y1, Left: x0}}
+	p.Padding = p.Insets
+	if x0, x1, ok := run(w, func(i int) bool { return marked(inner.Min.X+i, b.Max.Y-1) }); ok {
+		p.Padding.Left, p.Padding.Right = x0, w-1-x1
+	}
+	if y0, y1, ok := run(h, func(i int) bool { return marked(b.Max.X-1, inner.Min.Y+i) }); ok {
+		p.Padding.Top, p.Padding.Bottom = y0, h-1-y1
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), im, inner.Min, draw.Src)
+	p.Image = rgba
+	return p, nil
+}
@@ -0,0 +1,32 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNineSliceClipsLastTile(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			im.SetRGBA(x, y, color.RGBA{uint8(20*x + 50), 0, 0, 255})
+		}
+	}
+	c := NewCanvas(40, 20)
+	c.SetNineSliceMode(SliceTile, SliceTile)
+	c.DrawNineSlice(im, Insets{}, 0, 0, 24.5, 10)
+	for _, test := range []struct {
+		x    int
+		want color.RGBA
+	}{
+		{13, color.RGBA{110, 0, 0, 255}},
+		{23, color.RGBA{110, 0, 0, 255}},
+		{24, color.RGBA{65, 0, 0, 128}},
+		{25, color.RGBA{}},
+	} {
+		if got := c.im.RGBAAt(test.x, 5); got != test.want {
+			t.Errorf("pixel %d: %v, want %v", test.x, got, test.want)
+		}
+	}
+}
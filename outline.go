@@ -0,0 +1,93 @@
+package drawlib
+
+import (
+	"math"
+
+	"github.com/golang/freetype/raster"
+)
+
+// StrokeToPath returns the outline of what Stroke would paint for the
+// current path with the current line width, cap, join and dashes, in user
+// space. The pieces of the outline overlap, so fill it with
+// FillRuleWinding.
+func (c *Canvas) StrokeToPath() *Path {
+	return pathFromRaster(c.strokeOutline()).Transform(c.matrix.Invert())
+}
+
+// strokeOutline returns the outline of the current stroke in device space.
+func (c *Canvas) strokeOutline() raster.Path {
+	open, closed := c.dashedStrokePath()
+	var outline raster.Path
+	raster.Stroke(&outline, open, Fix(c.lineWidth), c.capper(), c.joiner())
+	raster.Stroke(&outline, closed, Fix(c.lineWidth), raster.ButtCapper, c.joiner())
+	return outline
+}
+
+// Offset returns p grown outwards by d, or shrunk inwards when d is
+// negative, with the moved corners shaped by join. Every subpath is
+// treated as closed, miters are limited as on a new Canvas and the result
+// is a flattened polygon outline.
+func (p *Path) Offset(d float64, join LineJoin) *Path {
+	if d == 0 {
+		return p.Copy()
+	}
+	// stroke in units fine enough that rounding to 1/64 stays far inside
+	// the tolerance, but coarse enough not to overflow 26.6
+	tolerance := p.flatteningTolerance()
+	x0, y0, x1, y1 := p.Bounds()
+	extent := math.Max(math.Max(math.Abs(x0), math.Abs(y0)), math.Max(math.Abs(x1), math.Abs(y1))) + math.Abs(d)
+	s := math.Min(64/tolerance, (1<<23)/extent)
+	var q raster.Path
+	lines, _ := p.flatten(tolerance)
+	for _, line := range lines {
+		if len(line) < 2 {
+			continue
+		}
+		first, last := line[0], line[len(line)-1]
+		if first.X != last.X || first.Y != last.Y {
+			line = append(line, first)
+		}
+		// run on past the start so the first corner is joined, not capped
+		line = append(line, line[1])
+		previous := Fixp(first.X*s, first.Y*s)
+		q.Start(previous)
+		for _, v := range line[1:] {
+			if f := Fixp(v.X*s, v.Y*s); f != previous {
+				q.Add1(f)
+				previous = f
+			}
+		}
+	}
+	var outline raster.Path
+	raster.Stroke(&outline, q, Fix(2*math.Abs(d)*s), raster.ButtCapper, joiner(join, defaultMiterLimit))
+	border := pathFromRaster(outline).Transform(Scale(1/s, 1/s)).SetTolerance(p.tolerance)
+	if d > 0 {
+		return p.Union(border)
+	}
+	return p.Difference(border)
+}
+
+// pathFromRaster converts a raster path into a Path, closing every contour
+// as the rasterizer does.
+func pathFromRaster(r raster.Path) *Path {
+	p := NewPath()
+	walkPath(r, func(op int, points []*Vector) {
+		switch op {
+		case 0:
+			if p.hasCurrent {
+				p.ClosePath()
+			}
+			p.MoveTo(points[0].X, points[0].Y)
+		case 1:
+			p.LineTo(points[0].X, points[0].Y)
+		case 2:
+			p.QuadraticTo(points[0].X, points[0].Y, points[1].X, points[1].Y)
+		case 3:
+			p.CubicTo(points[0].X, points[0].Y, points[1].X, points[1].Y, points[2].X, points[2].Y)
+		}
+	})
+	if p.hasCurrent {
+		p.ClosePath()
+	}
+	return p
+}
@@ -0,0 +1,41 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPathOffset(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		p      *Path
+		d      float64
+		bounds [4]float64
+		area   float64
+	}{
+		{"grow", NewPath().DrawRectangle(10, 10, 20, 20), 5, [4]float64{5, 5, 35, 35}, 900},
+		{"shrink", NewPath().DrawRectangle(10, 10, 20, 20), -5, [4]float64{15, 15, 25, 25}, 100},
+		{"small", NewPath().DrawRectangle(0.1, 0.1, 0.2, 0.2), 0.05, [4]float64{0.05, 0.05, 0.35, 0.35}, 0.09},
+		{"far", NewPath().DrawRectangle(1e5, 1e5, 10, 10), 1, [4]float64{1e5 - 1, 1e5 - 1, 1e5 + 11, 1e5 + 11}, 144},
+	} {
+		got := test.p.Offset(test.d, LineJoinMiter)
+		b := pathBounds(got)
+		for i := range b {
+			if math.Abs(b[i]-test.bounds[i]) > 1e-3 {
+				t.Errorf("%s: bounds %v, want %v", test.name, b, test.bounds)
+				break
+			}
+		}
+		if a := math.Abs(pathArea(got)); math.Abs(a-test.area) > 1e-3*test.area {
+			t.Errorf("%s: area %v, want %v", test.name, a, test.area)
+		}
+	}
+}
+
+func TestPathOffsetRound(t *testing.T) {
+	got := NewPath().DrawRectangle(0, 0, 10, 10).Offset(2, LineJoinRound)
+	want := 100 + 4*20 + 4*math.Pi
+	if a := math.Abs(pathArea(got)); math.Abs(a-want) > 0.5 {
+		t.Errorf("area %v, want about %v", a, want)
+	}
+}
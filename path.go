@@ -0,0 +1,638 @@
+package drawlib
+
+import (
+	"math"
+
+	"github.com/golang/freetype/raster"
+)
+
+const (
+	pathMoveTo = iota
+	pathLineTo
+	pathQuadraticTo
+	pathCubicTo
+	pathClose
+)
+
+type (
+	// Path is a reusable outline built with the same methods as the current
+	// path of a Canvas. Its coordinates are in the user space of the canvas
+	// that draws it.
+	Path struct {
+		segments   []pathSegment
+		start      Vector
+		current    Vector
+		hasCurrent bool
+		tolerance  float64
+	}
+
+	pathSegment struct {
+		op     int
+		points [3]Vector
+	}
+
+	// pathBuilder is implemented by Path and Canvas so that shapes and SVG
+	// path data can be added to either.
+	pathBuilder interface {
+		moveTo(x, y float64)
+		lineTo(x, y float64)
+		quadraticTo(x1, y1, x2, y2 float64)
+		cubicTo(x1, y1, x2, y2, x3, y3 float64)
+		closePath()
+		newSubPath()
+		hasCurrentPoint() bool
+		flatteningTolerance() float64
+	}
+)
+
+func NewPath() *Path {
+	return &Path{}
+}
+
+func (p *Path) add(op int, points ...Vector) {
+	s := pathSegment{op: op}
+	copy(s.points[:], points)
+	p.segments = append(p.segments, s)
+}
+
+func (p *Path) MoveTo(x, y float64) *Path {
+	v := Vector{x, y}
+	p.add(pathMoveTo, v)
+	p.start = v
+	p.current = v
+	p.hasCurrent = true
+	return p
+}
+
+func (p *Path) LineTo(x, y float64) *Path {
+	if !p.hasCurrent {
+		return p.MoveTo(x, y)
+	}
+	p.current = Vector{x, y}
+	p.add(pathLineTo, p.current)
+	return p
+}
+
+func (p *Path) QuadraticTo(x1, y1, x2, y2 float64) *Path {
+	if !p.hasCurrent {
+		p.MoveTo(x1, y1)
+	}
+	p.current = Vector{x2, y2}
+	p.add(pathQuadraticTo, Vector{x1, y1}, p.current)
+	return p
+}
+
+func (p *Path) CubicTo(x1, y1, x2, y2, x3, y3 float64) *Path {
+	if !p.hasCurrent {
+		p.MoveTo(x1, y1)
+	}
+	p.current = Vector{x3, y3}
+	p.add(pathCubicTo, Vector{x1, y1}, Vector{x2, y2}, p.current)
+	return p
+}
+
+func (p *Path) ClosePath() *Path {
+	if p.hasCurrent {
+		p.add(pathClose)
+		p.current = p.start
+	}
+	return p
+}
+
+func (p *Path) NewSubPath() *Path {
+	p.hasCurrent = false
+	return p
+}
+
+func (p *Path) Clear() *Path {
+	p.segments = nil
+	p.hasCurrent = false
+	return p
+}
+
+// SetTolerance sets how far in path units arcs and ellipses added to p,
+// and the flattening done by Combine, Offset and PathMeasure, may stray
+// from the true shape. The default is 0.1; paths meant to be drawn much
+// enlarged need a smaller value. Values that are not positive and finite
+// are ignored.
+func (p *Path) SetTolerance(tolerance float64) *Path {
+	if tolerance > 0 && !math.IsInf(tolerance, 1) {
+		p.tolerance = tolerance
+	}
+	return p
+}
+
+func (p *Path) IsEmpty() bool {
+	return len(p.segments) == 0
+}
+
+func (p *Path) Copy() *Path {
+	q := *p
+	q.segments = append([]pathSegment(nil), p.segments...)
+	return &q
+}
+
+func (p *Path) DrawLine(x1, y1, x2, y2 float64) *Path {
+	drawLine(p, x1, y1, x2, y2)
+	return p
+}
+
+func (p *Path) DrawRectangle(x, y, w, h float64) *Path {
+	drawRectangle(p, x, y, w, h)
+	return p
+}
+
+func (p *Path) DrawRoundedRectangle(x, y, w, h, r float64) *Path {
+	drawRoundedRectangle(p, x, y, w, h, r)
+	return p
+}
+
+func (p *Path) DrawEllipticalArc(x, y, rx, ry, angle1, angle2 float64) *Path {
+	drawEllipticalArc(p, x, y, rx, ry, angle1, angle2)
+	return p
+}
+
+func (p *Path) DrawEllipse(x, y, rx, ry float64) *Path {
+	drawEllipse(p, x, y, rx, ry)
+	return p
+}
+
+func (p *Path) DrawArc(x, y, r, angle1, angle2 float64) *Path {
+	drawEllipticalArc(p, x, y, r, r, angle1, angle2)
+	return p
+}
+
+func (p *Path) DrawCircle(x, y, r float64) *Path {
+	drawEllipse(p, x, y, r, r)
+	return p
+}
+
+func (p *Path) DrawRegularPolygon(n int, x, y, r, rotation float64) *Path {
+	drawRegularPolygon(p, n, x, y, r, rotation)
+	return p
+}
+
+// DrawSVGPath adds the path described by SVG path data to p. See
+// Canvas.DrawSVGPath.
+func (p *Path) DrawSVGPath(d string) error {
+	return drawSVGPath(p, d)
+}
+
+// Append adds the subpaths of q to the end of p.
+func (p *Path) Append(q *Path) *Path {
+	q.build(p)
+	return p
+}
+
+// Transform returns a copy of p with every point transformed by m.
+func (p *Path) Transform(m *Matrix) *Path {
+	q := p.Copy()
+	for i := range q.segments {
+		s := &q.segments[i]
+		for j := range s.points {
+			s.points[j].X, s.points[j].Y = m.TransformPoint(s.points[j].X, s.points[j].Y)
+		}
+	}
+	q.start.X, q.start.Y = m.TransformPoint(p.start.X, p.start.Y)
+	q.current.X, q.current.Y = m.TransformPoint(p.current.X, p.current.Y)
+	return q
+}
+
+// Reverse returns a copy of p with the direction of every subpath reversed.
+// The subpaths keep their order.
+func (p *Path) Reverse() *Path {
+	q := NewPath().SetTolerance(p.tolerance)
+	for _, sp := range p.subpaths() {
+		closed := sp[len(sp)-1].op == pathClose
+		if closed {
+			sp = sp[:len(sp)-1]
+		}
+		end := sp[len(sp)-1].end()
+		q.MoveTo(end.X, end.Y)
+		for i := len(sp) - 1; i > 0; i-- {
+			s := sp[i]
+			prev := sp[i-1].end()
+			switch s.op {
+			case pathLineTo:
+				q.LineTo(prev.X, prev.Y)
+			case pathQuadraticTo:
+				q.QuadraticTo(s.points[0].X, s.points[0].Y, prev.X, prev.Y)
+			case pathCubicTo:
+				q.CubicTo(s.points[1].X, s.points[1].Y, s.points[0].X, s.points[0].Y, prev.X, prev.Y)
+			}
+		}
+		if closed {
+			q.ClosePath()
+		}
+	}
+	return q
+}
+
+// Bounds returns the exact bounding box of p, curves included.
+func (p *Path) Bounds() (x0, y0, x1, y1 float64) {
+	first := true
+	add := func(v Vector) {
+		if first {
+			x0, y0, x1, y1 = v.X, v.Y, v.X, v.Y
+			first = false
+		}
+		x0, y0 = math.Min(x0, v.X), math.Min(y0, v.Y)
+		x1, y1 = math.Max(x1, v.X), math.Max(y1, v.Y)
+	}
+	var start, prev Vector
+	for _, s := range p.segments {
+		switch s.op {
+		case pathMoveTo:
+			start = s.points[0]
+			add(start)
+		case pathLineTo:
+			add(s.points[0])
+		case pathQuadraticTo:
+			add(s.points[1])
+			for _, t := range quadraticExtrema(prev, s.points[0], s.points[1]) {
+				add(quadraticPoint(prev, s.points[0], s.points[1], t))
+			}
+		case pathCubicTo:
+			add(s.points[2])
+			for _, t := range cubicExtrema(prev, s.points[0], s.points[1], s.points[2]) {
+				add(cubicPoint(prev, s.points[0], s.points[1], s.points[2], t))
+			}
+		}
+		prev = s.end()
+		if s.op == pathClose {
+			prev = start
+		}
+	}
+	return
+}
+
+// build replays p into b.
+func (p *Path) build(b pathBuilder) {
+	for _, s := range p.segments {
+		q := s.points
+		switch s.op {
+		case pathMoveTo:
+			b.moveTo(q[0].X, q[0].Y)
+		case pathLineTo:
+			b.lineTo(q[0].X, q[0].Y)
+		case pathQuadraticTo:
+			b.quadraticTo(q[0].X, q[0].Y, q[1].X, q[1].Y)
+		case pathCubicTo:
+			b.cubicTo(q[0].X, q[0].Y, q[1].X, q[1].Y, q[2].X, q[2].Y)
+		case pathClose:
+			b.closePath()
+		}
+	}
+}
+
+// subpaths splits p into subpaths that each begin with a move and end with
+// at most one close. Drawing after a close starts a new subpath at the
+// point closed to.
+func (p *Path) subpaths() [][]pathSegment {
+	var result [][]pathSegment
+	var sp []pathSegment
+	var start Vector
+	for _, s := range p.segments {
+		switch s.op {
+		case pathMoveTo:
+			if sp != nil {
+				result = append(result, sp)
+			}
+			sp = []pathSegment{s}
+			start = s.points[0]
+		case pathClose:
+			if sp != nil {
+				result = append(result, append(sp, s))
+				sp = nil
+			}
+		default:
+			if sp == nil {
+				sp = []pathSegment{{op: pathMoveTo, points: [3]Vector{start}}}
+			}
+			sp = append(sp, s)
+		}
+	}
+	if sp != nil {
+		result = append(result, sp)
+	}
+	return result
+}
+
+// flatten returns the subpaths of p as polylines within tolerance of the
+// curves, and which of them were ended by a close. Closed subpaths end back
+// at their first point.
+func (p *Path) flatten(tolerance float64) (lines [][]*Vector, closed []bool) {
+	for _, sp := range p.subpaths() {
+		start := sp[0].points[0]
+		line := []*Vector{NewVector(start.X, start.Y)}
+		prev := start
+		for _, s := range sp[1:] {
+			q := s.points
+			switch s.op {
+			case pathLineTo:
+				line = append(line, NewVector(q[0].X, q[0].Y))
+			case pathQuadraticTo:
+				line = append(line, flattenQuadratic(prev.X, prev.Y, q[0].X, q[0].Y, q[1].X, q[1].Y, tolerance)[1:]...)
+			case pathCubicTo:
+				line = append(line, flattenCubic(prev.X, prev.Y, q[0].X, q[0].Y, q[1].X, q[1].Y, q[2].X, q[2].Y, tolerance)[1:]...)
+			case pathClose:
+				line = append(line, NewVector(start.X, start.Y))
+			}
+			prev = s.end()
+		}
+		lines = append(lines, line)
+		closed = append(closed, sp[len(sp)-1].op == pathClose)
+	}
+	return lines, closed
+}
+
+// fixedPath returns p as a raster path for the recorders. Every subpath
+// starts afresh and a close becomes a line back to its start; closed
+// reports which subpaths were closed.
+func (p *Path) fixedPath() (path raster.Path, closed []bool) {
+	for _, sp := range p.subpaths() {
+		start := sp[0].points[0]
+		path.Start(start.Fixed())
+		for _, s := range sp[1:] {
+			q := s.points
+			switch s.op {
+			case pathLineTo:
+				path.Add1(q[0].Fixed())
+			case pathQuadraticTo:
+				path.Add2(q[0].Fixed(), q[1].Fixed())
+			case pathCubicTo:
+				path.Add3(q[0].Fixed(), q[1].Fixed(), q[2].Fixed())
+			case pathClose:
+				path.Add1(start.Fixed())
+			}
+		}
+		closed = append(closed, sp[len(sp)-1].op == pathClose)
+	}
+	return path, closed
+}
+
+// pathFromFixed turns a raster path made by fixedPath back into a Path,
+// closing the subpaths flagged in closed.
+func pathFromFixed(r raster.Path, closed []bool) *Path {
+	p := NewPath()
+	walkStroke(r, closed, func(op int, points []*Vector) {
+		switch op {
+		case 0:
+			p.MoveTo(points[0].X, points[0].Y)
+		case 1:
+			p.LineTo(points[0].X, points[0].Y)
+		case 2:
+			p.QuadraticTo(points[0].X, points[0].Y, points[1].X, points[1].Y)
+		case 3:
+			p.CubicTo(points[0].X, points[0].Y, points[1].X, points[1].Y, points[2].X, points[2].Y)
+		case 4:
+			p.ClosePath()
+		}
+	})
+	return p
+}
+
+// end returns the point s finishes at. It is not defined for a close.
+func (s pathSegment) end() Vector {
+	switch s.op {
+	case pathQuadraticTo:
+		return s.points[1]
+	case pathCubicTo:
+		return s.points[2]
+	}
+	return s.points[0]
+}
+
+// FillPath fills p with the current fill style and matrix, leaving the
+// current path untouched.
+func (c *Canvas) FillPath(p *Path) *Canvas {
+	saved := c.takePath()
+	p.build(c)
+	c.FillPreserve()
+	c.restorePath(saved)
+	return c
+}
+
+// StrokePath strokes p with the current stroke style and matrix, leaving
+// the current path untouched.
+func (c *Canvas) StrokePath(p *Path) *Canvas {
+	saved := c.takePath()
+	p.build(c)
+	c.StrokePreserve()
+	c.restorePath(saved)
+	return c
+}
+
+// ClipPath intersects the clip with p, leaving the current path untouched.
+func (c *Canvas) ClipPath(p *Path) *Canvas {
+	saved := c.takePath()
+	p.build(c)
+	c.ClipPreserve()
+	c.restorePath(saved)
+	return c
+}
+
+// AppendPath adds p to the current path.
+func (c *Canvas) AppendPath(p *Path) *Canvas {
+	p.build(c)
+	return c
+}
+
+// CurrentPath returns a copy of the current path in the user space of the
+// current matrix.
+func (c *Canvas) CurrentPath() *Path {
+	return c.path.Transform(c.matrix.Invert())
+}
+
+// takePath clears the current path and returns it for restorePath.
+func (c *Canvas) takePath() *Canvas {
+	saved := &Canvas{
+		fillPath:   c.fillPath,
+		path:       c.path,
+		start:      c.start,
+		current:    c.current,
+		hasCurrent: c.hasCurrent,
+	}
+	c.fillPath, c.path, c.hasCurrent = nil, Path{}, false
+	return saved
+}
+
+func (c *Canvas) restorePath(saved *Canvas) {
+	c.fillPath, c.path = saved.fillPath, saved.path
+	c.start, c.current, c.hasCurrent = saved.start, saved.current, saved.hasCurrent
+}
+
+func (p *Path) moveTo(x, y float64)                      { p.MoveTo(x, y) }
+func (p *Path) lineTo(x, y float64)                      { p.LineTo(x, y) }
+func (p *Path) quadraticTo(x1, y1, x2, y2 float64)       { p.QuadraticTo(x1, y1, x2, y2) }
+func (p *Path) cubicTo(x1, y1, x2, y2, x3, y3 float64)   { p.CubicTo(x1, y1, x2, y2, x3, y3) }
+func (p *Path) closePath()                               { p.ClosePath() }
+func (p *Path) newSubPath()                              { p.NewSubPath() }
+func (p *Path) hasCurrentPoint() bool                    { return p.hasCurrent }
+func (c *Canvas) moveTo(x, y float64)                    { c.MoveTo(x, y) }
+func (c *Canvas) lineTo(x, y float64)                    { c.LineTo(x, y) }
+func (c *Canvas) quadraticTo(x1, y1, x2, y2 float64)     { c.QuadraticTo(x1, y1, x2, y2) }
+func (c *Canvas) cubicTo(x1, y1, x2, y2, x3, y3 float64) { c.CubicTo(x1, y1, x2, y2, x3, y3) }
+func (c *Canvas) closePath()                             { c.ClosePath() }
+func (c *Canvas) newSubPath()                            { c.NewSubPath() }
+func (c *Canvas) hasCurrentPoint() bool                  { return c.hasCurrent }
+
+func (p *Path) flatteningTolerance() float64 {
+	if p.tolerance > 0 {
+		return p.tolerance
+	}
+	return defaultTolerance
+}
+
+// flatteningTolerance returns the canvas tolerance in user space.
+func (c *Canvas) flatteningTolerance() float64 {
+	if s := c.matrix.maxScale(); s > 0 {
+		return c.tolerance / s
+	}
+	return c.tolerance
+}
+
+func drawLine(b pathBuilder, x1, y1, x2, y2 float64) {
+	b.moveTo(x1, y1)
+	b.lineTo(x2, y2)
+}
+
+func drawRectangle(b pathBuilder, x, y, w, h float64) {
+	b.newSubPath()
+	b.moveTo(x, y)
+	b.lineTo(x+w, y)
+	b.lineTo(x+w, y+h)
+	b.lineTo(x, y+h)
+	b.closePath()
+}
+
+func drawRoundedRectangle(b pathBuilder, x, y, w, h, r float64) {
+	x0, x1, x2, x3 := x, x+r, x+w-r, x+w
+	y0, y1, y2, y3 := y, y+r, y+h-r, y+h
+	b.newSubPath()
+	b.moveTo(x1, y0)
+	b.lineTo(x2, y0)
+	drawEllipticalArc(b, x2, y1, r, r, ToRadians(270), ToRadians(360))
+	b.lineTo(x3, y2)
+	drawEllipticalArc(b, x2, y2, r, r, ToRadians(0), ToRadians(90))
+	b.lineTo(x1, y3)
+	drawEllipticalArc(b, x1, y2, r, r, ToRadians(90), ToRadians(180))
+	b.lineTo(x0, y1)
+	drawEllipticalArc(b, x1, y1, r, r, ToRadians(180), ToRadians(270))
+	b.closePath()
+}
+
+func drawEllipticalArc(b pathBuilder, x, y, rx, ry, angle1, angle2 float64) {
+	// each quadratic below strays about 0.002*r*a⁴ from an arc of a radians;
+	// flattening the quadratics takes the other half of the tolerance
+	r := math.Max(math.Abs(rx), math.Abs(ry))
+	step := math.Pi / 2
+	if r > 0 {
+		step = math.Min(step, math.Pow(b.flatteningTolerance()/2/(0.002*r), 0.25))
+	}
+	n := int(math.Max(1, math.Ceil(math.Abs(angle2-angle1)/step)))
+	for i := 0; i < n; i++ {
+		p1 := float64(i+0) / float64(n)
+		p2 := float64(i+1) / float64(n)
+		a1 := angle1 + (angle2-angle1)*p1
+		a2 := angle1 + (angle2-angle1)*p2
+		x0 := x + rx*math.Cos(a1)
+		y0 := y + ry*math.Sin(a1)
+		x1 := x + rx*math.Cos(a1+(a2-a1)/2)
+		y1 := y + ry*math.Sin(a1+(a2-a1)/2)
+		x2 := x + rx*math.Cos(a2)
+		y2 := y + ry*math.Sin(a2)
+		cx := 2*x1 - x0/2 - x2/2
+		cy := 2*y1 - y0/2 - y2/2
+		if i == 0 {
+			if b.hasCurrentPoint() {
+				b.lineTo(x0, y0)
+			} else {
+				b.moveTo(x0, y0)
+			}
+		}
+		b.quadraticTo(cx, cy, x2, y2)
+	}
+}
+
+func drawEllipse(b pathBuilder, x, y, rx, ry float64) {
+	b.newSubPath()
+	drawEllipticalArc(b, x, y, rx, ry, 0, 2*math.Pi)
+	b.closePath()
+}
+
+func drawRegularPolygon(b pathBuilder, n int, x, y, r, rotation float64) {
+	angle := 2 * math.Pi / float64(n)
+	rotation -= math.Pi / 2
+	if n%2 == 0 {
+		rotation += angle / 2
+	}
+	b.newSubPath()
+	for i := 0; i < n; i++ {
+		a := rotation + angle*float64(i)
+		b.lineTo(x+r*math.Cos(a), y+r*math.Sin(a))
+	}
+	b.closePath()
+}
+
+func quadraticPoint(p0, p1, p2 Vector, t float64) Vector {
+	u := 1 - t
+	a, b, c := u*u, 2*u*t, t*t
+	return Vector{a*p0.X + b*p1.X + c*p2.X, a*p0.Y + b*p1.Y + c*p2.Y}
+}
+
+func cubicPoint(p0, p1, p2, p3 Vector, t float64) Vector {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return Vector{
+		a*p0.X + b*p1.X + c*p2.X + d*p3.X,
+		a*p0.Y + b*p1.Y + c*p2.Y + d*p3.Y,
+	}
+}
+
+// quadraticExtrema returns the parameters strictly inside (0, 1) where the
+// curve turns in x or y.
+func quadraticExtrema(p0, p1, p2 Vector) []float64 {
+	var result []float64
+	for _, v := range [][3]float64{{p0.X, p1.X, p2.X}, {p0.Y, p1.Y, p2.Y}} {
+		if d := v[0] - 2*v[1] + v[2]; d != 0 {
+			if t := (v[0] - v[1]) / d; t > 0 && t < 1 {
+				result = append(result, t)
+			}
+		}
+	}
+	return result
+}
+
+// cubicExtrema returns the parameters strictly inside (0, 1) where the curve
+// turns in x or y.
+func cubicExtrema(p0, p1, p2, p3 Vector) []float64 {
+	var result []float64
+	for _, v := range [][4]float64{{p0.X, p1.X, p2.X, p3.X}, {p0.Y, p1.Y, p2.Y, p3.Y}} {
+		a := -v[0] + 3*v[1] - 3*v[2] + v[3]
+		b := 2 * (v[0] - 2*v[1] + v[2])
+		c := v[1] - v[0]
+		for _, t := range solveQuadratic(a, b, c) {
+			if t > 0 && t < 1 {
+				result = append(result, t)
+			}
+		}
+	}
+	return result
+}
+
+// solveQuadratic returns the real roots of a*t*t + b*t + c.
+func solveQuadratic(a, b, c float64) []float64 {
+	if math.Abs(a) < 1e-12 {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	d := b*b - 4*a*c
+	if d < 0 {
+		return nil
+	}
+	d = math.Sqrt(d)
+	return []float64{(-b - d) / (2 * a), (-b + d) / (2 * a)}
+}
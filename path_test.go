@@ -0,0 +1,100 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+// boundsNear allows for arcs, which are approximated within the default
+// tolerance.
+func boundsNear(got, want [4]float64) bool {
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > defaultTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func pathBounds(p *Path) [4]float64 {
+	x0, y0, x1, y1 := p.Bounds()
+	return [4]float64{x0, y0, x1, y1}
+}
+
+func TestPathBounds(t *testing.T) {
+	for name, test := range map[string]struct {
+		p    *Path
+		want [4]float64
+	}{
+		"empty":     {NewPath(), [4]float64{}},
+		"rectangle": {NewPath().DrawRectangle(10, 20, 30, 40), [4]float64{10, 20, 40, 60}},
+		"circle":    {NewPath().DrawCircle(50, 50, 10), [4]float64{40, 40, 60, 60}},
+		"ellipse":   {NewPath().DrawEllipse(0, 0, 20, 5), [4]float64{-20, -5, 20, 5}},
+		"quadratic": {NewPath().MoveTo(0, 0).QuadraticTo(10, 20, 20, 0), [4]float64{0, 0, 20, 10}},
+		"cubic":     {NewPath().MoveTo(0, 0).CubicTo(0, -20, 20, -20, 20, 0), [4]float64{0, -15, 20, 0}},
+		"subpaths":  {NewPath().DrawRectangle(0, 0, 1, 1).DrawRectangle(-5, 3, 1, 1), [4]float64{-5, 0, 1, 4}},
+		"after close": {
+			NewPath().MoveTo(0, 0).LineTo(4, 0).ClosePath().QuadraticTo(-4, 8, 0, 0),
+			[4]float64{-2, 0, 4, 4},
+		},
+	} {
+		if got := pathBounds(test.p); !boundsNear(got, test.want) {
+			t.Errorf("%s: bounds %v, want %v", name, got, test.want)
+		}
+	}
+}
+
+func TestPathTransform(t *testing.T) {
+	p := NewPath().DrawRectangle(0, 0, 10, 10)
+	q := p.Transform(Translate(5, 0).Multiply(*Scale(2, 3)))
+	if got, want := pathBounds(q), [4]float64{10, 0, 30, 30}; !boundsNear(got, want) {
+		t.Errorf("transformed bounds %v, want %v", got, want)
+	}
+	if got, want := pathBounds(p), [4]float64{0, 0, 10, 10}; !boundsNear(got, want) {
+		t.Errorf("Transform changed the original: %v", got)
+	}
+}
+
+func TestPathReverse(t *testing.T) {
+	p := NewPath().MoveTo(0, 0).LineTo(10, 0).CubicTo(10, 5, 5, 10, 0, 10).ClosePath()
+	r := p.Reverse()
+	if got, want := pathBounds(r), pathBounds(p); !boundsNear(got, want) {
+		t.Errorf("reversed bounds %v, want %v", got, want)
+	}
+	if got := r.segments[0].points[0]; got != (Vector{0, 10}) {
+		t.Errorf("reversed path starts at %v, want {0 10}", got)
+	}
+	if r.segments[len(r.segments)-1].op != pathClose {
+		t.Error("reversed path is not closed")
+	}
+	pl, _ := p.flatten(0.01)
+	rl, _ := r.flatten(0.01)
+	if a, b := polylineArea(pl[0]), polylineArea(rl[0]); math.Abs(a+b) > 1e-6 {
+		t.Errorf("signed areas %v and %v, want opposite", a, b)
+	}
+}
+
+func TestCanvasPathUntouched(t *testing.T) {
+	c := NewCanvas(20, 20)
+	c.MoveTo(1, 1)
+	c.LineTo(5, 1)
+	c.FillPath(NewPath().DrawRectangle(0, 0, 10, 10))
+	c.StrokePath(NewPath().DrawCircle(10, 10, 5))
+	got := c.CurrentPath()
+	if len(got.segments) != 2 || got.current != (Vector{5, 1}) {
+		t.Errorf("current path changed to %v", got.segments)
+	}
+	if px := c.im.RGBAAt(5, 5); px.A == 0 {
+		t.Error("FillPath drew nothing")
+	}
+}
+
+// polylineArea returns the signed area of a closed polyline.
+func polylineArea(line []*Vector) float64 {
+	a := 0.0
+	for i, v := range line {
+		w := line[(i+1)%len(line)]
+		a += v.X*w.Y - w.X*v.Y
+	}
+	return a / 2
+}
@@ -27,9 +27,12 @@ type (
 		op RepeatOp
 	}
 	patternPainter struct {
-		im   *image.RGBA
-		mask *image.Alpha
-		p    Pattern
+		im      *image.RGBA
+		mask    *image.Alpha
+		p       Pattern
+		mode    BlendMode
+		op      CompositeOp
+		covered *image.Alpha
 	}
 )
 
@@ -73,7 +76,7 @@ func (r *patternPainter) Paint(ss []raster.Span, done bool) {
 			continue
 		}
 		if s.Y >= b.Max.Y {
-			return
+			break
 		}
 		if s.X0 < b.Min.X {
 			s.X0 = b.Min.X
@@ -90,15 +93,28 @@ func (r *patternPainter) Paint(ss []raster.Span, done bool) {
 		i0 := (s.Y-r.im.Rect.Min.Y)*r.im.Stride + (s.X0-r.im.Rect.Min.X)*4
 		i1 := i0 + (s.X1-s.X0)*4
 		for i, x := i0, x0; i < i1; i, x = i+4, x+1 {
+			if r.covered != nil {
+				r.covered.Pix[y*r.covered.Stride+x] = 0xff
+			}
+			// the pattern and the mask are in device space
+			dx := x + r.im.Rect.Min.X
 			ma := s.Alpha
 			if r.mask != nil {
-				ma = ma * uint32(r.mask.AlphaAt(x, y).A) / 255
+				ma = ma * uint32(r.mask.AlphaAt(dx, s.Y).A) / 255
 				if ma == 0 {
 					continue
 				}
 			}
-			c := r.p.ColorAt(x, y)
+			c := r.p.ColorAt(dx, s.Y)
 			cr, cg, cb, ca := c.RGBA()
+			if r.mode != BlendNormal || r.op != CompositeSourceOver {
+				clip := uint32(0xffff)
+				if r.mask != nil {
+					clip = uint32(r.mask.AlphaAt(dx, s.Y).A) * 0x101
+				}
+				compositePixel(r.im.Pix[i:i+4], cr, cg, cb, ca, s.Alpha, clip, r.mode, r.op)
+				continue
+			}
 			dr := uint32(r.im.Pix[i+0])
 			dg := uint32(r.im.Pix[i+1])
 			db := uint32(r.im.Pix[i+2])
@@ -110,8 +126,33 @@ func (r *patternPainter) Paint(ss []raster.Span, done bool) {
 			r.im.Pix[i+3] = uint8((da*a + ca*ma) / m >> 8)
 		}
 	}
+	if done && r.covered != nil {
+		r.paintUncovered()
+	}
+}
+
+func newPatternPainter(im *image.RGBA, mask *image.Alpha, p Pattern, mode BlendMode, op CompositeOp) *patternPainter {
+	r := &patternPainter{im: im, mask: mask, p: p, mode: mode, op: op}
+	if op.unbounded() {
+		r.covered = image.NewAlpha(image.Rect(0, 0, im.Rect.Dx(), im.Rect.Dy()))
+	}
+	return r
 }
 
-func newPatternPainter(im *image.RGBA, mask *image.Alpha, p Pattern) *patternPainter {
-	return &patternPainter{im, mask, p}
+// paintUncovered applies an unbounded operator with a transparent source to
+// the pixels that no span covered.
+func (r *patternPainter) paintUncovered() {
+	for y := 0; y < r.covered.Rect.Dy(); y++ {
+		for x := 0; x < r.covered.Rect.Dx(); x++ {
+			if r.covered.Pix[y*r.covered.Stride+x] != 0 {
+				continue
+			}
+			clip := uint32(0xffff)
+			if r.mask != nil {
+				clip = uint32(r.mask.AlphaAt(x+r.im.Rect.Min.X, y+r.im.Rect.Min.Y).A) * 0x101
+			}
+			i := y*r.im.Stride + x*4
+			compositePixel(r.im.Pix[i:i+4], 0, 0, 0, 0, 0, clip, r.mode, r.op)
+		}
+	}
 }
@@ -0,0 +1,492 @@
+package drawlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/freetype/raster"
+	"golang.org/x/image/draw"
+)
+
+type (
+	// PDFCanvas is a Canvas that also records every drawing operation into a
+	// multi-page PDF document. Text drawn with a font from LoadFontFace is
+	// written as glyph outlines. Gradients with translucent stops are
+	// sampled into an image, as shadings have no alpha.
+	PDFCanvas struct {
+		*Canvas
+		pdf *pdfRecorder
+	}
+	pdfResource struct {
+		category, name string
+		object         int
+	}
+	pdfRecorder struct {
+		width, height int
+		objects       [][]byte
+		resources     []pdfResource
+		pages         []*bytes.Buffer
+		page          *bytes.Buffer
+		clips         []string
+		patterns      map[paintKey]string
+		states        map[string]string
+	}
+)
+
+func NewPDFCanvas(width, height int) *PDFCanvas {
+	r := &pdfRecorder{
+		width:    width,
+		height:   height,
+		objects:  make([][]byte, 2),
+		patterns: map[paintKey]string{},
+		states:   map[string]string{},
+	}
+	r.beginPage()
+	c := NewCanvas(width, height)
+	c.recorder = r
+	return &PDFCanvas{Canvas: c, pdf: r}
+}
+
+// NewPage finishes the current page and starts a blank one. The active
+// clip carries over to the new page.
+func (c *PDFCanvas) NewPage() *PDFCanvas {
+	c.pdf.endPage()
+	c.pdf.beginPage()
+	draw.Draw(c.im, c.im.Bounds(), image.Transparent, image.ZP, draw.Src)
+	return c
+}
+
+func (c *PDFCanvas) EncodePDF(w io.Writer) error {
+	return c.pdf.encode(w)
+}
+
+func (c *PDFCanvas) SavePDF(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.pdf.encode(file)
+}
+
+func (r *pdfRecorder) beginPage() {
+	r.page = &bytes.Buffer{}
+	r.pages = append(r.pages, r.page)
+	r.writeHeader()
+}
+
+func (r *pdfRecorder) writeHeader() {
+	// flip the page so that drawing uses the canvas coordinates
+	fmt.Fprintf(r.page, "1 0 0 -1 0 %d cm\n", r.height)
+	for _, clip := range r.clips {
+		fmt.Fprintf(r.page, "q\n%s", clip)
+	}
+}
+
+func (r *pdfRecorder) endPage() {
+	for range r.clips {
+		r.page.WriteString("Q\n")
+	}
+}
+
+func (r *pdfRecorder) addObject(data []byte) int {
+	r.objects = append(r.objects, data)
+	return len(r.objects)
+}
+
+func (r *pdfRecorder) addResource(category, prefix string, object int) string {
+	name := prefix + strconv.Itoa(len(r.resources)+1)
+	r.resources = append(r.resources, pdfResource{category, name, object})
+	return name
+}
+
+func (r *pdfRecorder) fill(c *Canvas, path raster.Path, pattern Pattern) {
+	r.page.WriteString("q\n")
+	r.writeBlendMode(c)
+	r.writePaint(c, pattern, false)
+	writePDFPath(r.page, path, nil)
+	if c.fillRule == FillRuleEvenOdd {
+		r.page.WriteString("f*\nQ\n")
+	} else {
+		r.page.WriteString("f\nQ\n")
+	}
+}
+
+func (r *pdfRecorder) stroke(c *Canvas, path raster.Path, closed []bool, pattern Pattern) {
+	r.page.WriteString("q\n")
+	r.writeBlendMode(c)
+	r.writePaint(c, pattern, true)
+	fmt.Fprintf(r.page, "%s w\n", pdfNumber(c.lineWidth))
+	switch c.lineCap {
+	case LineCapButt:
+		r.page.WriteString("0 J\n")
+	case LineCapRound:
+		r.page.WriteString("1 J\n")
+	case LineCapSquare:
+		r.page.WriteString("2 J\n")
+	}
+	switch c.lineJoin {
+	case LineJoinRound:
+		r.page.WriteString("1 j\n")
+	case LineJoinBevel:
+		r.page.WriteString("2 j\n")
+	case LineJoinMiter:
+		fmt.Fprintf(r.page, "0 j\n%s M\n", pdfNumber(c.miterLimit))
+	}
+	if len(c.dashes) > 0 {
+		dashes := make([]string, len(c.dashes))
+		for i, d := range c.dashes {
+			dashes[i] = pdfNumber(d)
+		}
+		fmt.Fprintf(r.page, "[%s] %s d\n", strings.Join(dashes, " "), pdfNumber(c.dashOffset))
+	}
+	writePDFPath(r.page, path, closed)
+	r.page.WriteString("S\nQ\n")
+}
+
+func (r *pdfRecorder) clip(c *Canvas, path raster.Path) {
+	var b bytes.Buffer
+	writePDFPath(&b, path, nil)
+	if c.fillRule == FillRuleEvenOdd {
+		b.WriteString("W* n\n")
+	} else {
+		b.WriteString("W n\n")
+	}
+	r.clips = append(r.clips, b.String())
+	fmt.Fprintf(r.page, "q\n%s", b.String())
+}
+
+func (r *pdfRecorder) resetClip(c *Canvas) {
+	r.endPage()
+	r.clips = nil
+}
+
+// clear replaces the whole page, so its content recorded so far is dropped.
+func (r *pdfRecorder) clear(c *Canvas) {
+	r.page.Reset()
+	r.writeHeader()
+	n := color.NRGBAModel.Convert(c.clearSrc.C).(color.NRGBA)
+	if n.A == 0 {
+		return
+	}
+	r.page.WriteString("q\n")
+	r.writeColor(n, false)
+	fmt.Fprintf(r.page, "0 0 %d %d re\nf\nQ\n", r.width, r.height)
+}
+
+func (r *pdfRecorder) image(c *Canvas, im image.Image, m *Matrix) {
+	name := r.addImage(im)
+	b := im.Bounds()
+	r.page.WriteString("q\n")
+	r.writeBlendMode(c)
+	fmt.Fprintf(r.page, "%s cm\n%d 0 0 %d %d %d cm\n/%s Do\nQ\n",
+		pdfMatrix(m), b.Dx(), -b.Dy(), b.Min.X, b.Max.Y, name)
+}
+
+func (r *pdfRecorder) text(c *Canvas, s string, x, y float64) {
+	if c.font == nil {
+		// bitmap faces have no outlines, so the text is placed as an image
+		if im, m := c.textImage(s, x, y); !im.Rect.Empty() {
+			r.image(c, im, m)
+		}
+		return
+	}
+	r.fill(c, c.stringPath(s, x, y), NewSolidPattern(c.color))
+}
+
+func (r *pdfRecorder) writePaint(c *Canvas, pattern Pattern, stroke bool) {
+	if p, ok := pattern.(*solidPattern); ok {
+		r.writeColor(color.NRGBAModel.Convert(p.color).(color.NRGBA), stroke)
+		return
+	}
+	name := r.addPattern(c, pattern)
+	if stroke {
+		fmt.Fprintf(r.page, "/Pattern CS /%s SCN\n", name)
+	} else {
+		fmt.Fprintf(r.page, "/Pattern cs /%s scn\n", name)
+	}
+}
+
+func (r *pdfRecorder) writeColor(n color.NRGBA, stroke bool) {
+	op, alpha := "rg", "ca"
+	if stroke {
+		op, alpha = "RG", "CA"
+	}
+	if n.A != 255 {
+		r.writeState("/" + alpha + " " + pdfNumber(float64(n.A)/255))
+	}
+	fmt.Fprintf(r.page, "%s %s %s %s\n", pdfNumber(float64(n.R)/255),
+		pdfNumber(float64(n.G)/255), pdfNumber(float64(n.B)/255), op)
+}
+
+// writeBlendMode selects the canvas blend mode unless it is normal.
+func (r *pdfRecorder) writeBlendMode(c *Canvas) {
+	if c.blendMode == BlendNormal {
+		return
+	}
+	name := ""
+	for _, word := range strings.Split(c.blendMode.String(), "-") {
+		name += strings.ToUpper(word[:1]) + word[1:]
+	}
+	r.writeState("/BM /" + name)
+}
+
+// writeState selects a graphics state with the given entries, defining it
+// the first time it is used.
+func (r *pdfRecorder) writeState(entries string) {
+	name, ok := r.states[entries]
+	if !ok {
+		object := r.addObject([]byte("<< /Type /ExtGState " + entries + " >>"))
+		name = r.addResource("ExtGState", "GS", object)
+		r.states[entries] = name
+	}
+	fmt.Fprintf(r.page, "/%s gs\n", name)
+}
+
+func (r *pdfRecorder) addPattern(c *Canvas, pattern Pattern) string {
+	key := newPaintKey(c, pattern)
+	if cacheablePattern(pattern) {
+		if name, ok := r.patterns[key]; ok {
+			return name
+		}
+	}
+	// shadings map the gradient's space through the canvas matrix to the
+	// flipped page
+	flip := Matrix{1, 0, 0, -1, 0, float64(r.height)}
+	var object int
+	switch p := pattern.(type) {
+	case *linearGradient:
+		if p.spread != SpreadPad || !p.stops.opaque() {
+			object = r.addSampledPattern(c, pattern)
+			break
+		}
+		object = r.addObject([]byte(fmt.Sprintf(
+			"<< /PatternType 2 /Matrix [%s] /Shading << /ShadingType 2 /ColorSpace /DeviceRGB /Coords [%s %s %s %s] /Extend [true true] /Function %s >> >>",
+			pdfMatrix(p.deviceMatrix(c.matrix).Multiply(flip)),
+			pdfNumber(p.x0), pdfNumber(p.y0), pdfNumber(p.x1), pdfNumber(p.y1), pdfFunction(p.stops))))
+	case *radialGradient:
+		if p.spread != SpreadPad || !p.stops.opaque() {
+			object = r.addSampledPattern(c, pattern)
+			break
+		}
+		object = r.addObject([]byte(fmt.Sprintf(
+			"<< /PatternType 2 /Matrix [%s] /Shading << /ShadingType 3 /ColorSpace /DeviceRGB /Coords [%s %s %s %s %s %s] /Extend [true true] /Function %s >> >>",
+			pdfMatrix(p.deviceMatrix(c.matrix).Multiply(flip)),
+			pdfNumber(p.c0.x), pdfNumber(p.c0.y), pdfNumber(p.c0.r),
+			pdfNumber(p.c1.x), pdfNumber(p.c1.y), pdfNumber(p.c1.r), pdfFunction(p.stops))))
+	default:
+		object = r.addSampledPattern(c, pattern)
+	}
+	name := r.addResource("Pattern", "P", object)
+	if cacheablePattern(pattern) {
+		r.patterns[key] = name
+	}
+	return name
+}
+
+// addSampledPattern samples pattern over the page into an image tiled once.
+// It serves the patterns, gradient spreads and translucent stops PDF
+// shadings cannot express.
+func (r *pdfRecorder) addSampledPattern(c *Canvas, pattern Pattern) int {
+	sampled := c.devicePattern(pattern)
+	im := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			im.Set(x, y, sampled.ColorAt(x, y))
+		}
+	}
+	imageObject := r.addImageObject(im)
+	content := fmt.Sprintf("q\n%d 0 0 %d 0 %d cm\n/Im Do\nQ\n", c.width, -c.height, c.height)
+	return r.addObject(pdfStream(fmt.Sprintf(
+		"/PatternType 1 /PaintType 1 /TilingType 1 /BBox [0 0 %d %d] /XStep %d /YStep %d /Matrix [1 0 0 -1 0 %d] /Resources << /XObject << /Im %d 0 R >> >>",
+		c.width, c.height, c.width, c.height, r.height, imageObject), []byte(content)))
+}
+
+func (r *pdfRecorder) addImage(im image.Image) string {
+	return r.addResource("XObject", "Im", r.addImageObject(im))
+}
+
+func (r *pdfRecorder) addImageObject(im image.Image) int {
+	b := im.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			n := color.NRGBAModel.Convert(im.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, n.R, n.G, n.B)
+			alpha = append(alpha, n.A)
+			if n.A != 255 {
+				opaque = false
+			}
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8 /Filter /FlateDecode", b.Dx(), b.Dy())
+	smask := ""
+	if !opaque {
+		object := r.addObject(pdfStream(dict+" /ColorSpace /DeviceGray", pdfDeflate(alpha)))
+		smask = fmt.Sprintf(" /SMask %d 0 R", object)
+	}
+	return r.addObject(pdfStream(dict+" /ColorSpace /DeviceRGB"+smask, pdfDeflate(rgb)))
+}
+
+func (r *pdfRecorder) encode(w io.Writer) error {
+	objects := make([][]byte, len(r.objects))
+	copy(objects, r.objects)
+	add := func(data []byte) int {
+		objects = append(objects, data)
+		return len(objects)
+	}
+
+	var resources bytes.Buffer
+	resources.WriteString("<<")
+	for _, category := range []string{"ExtGState", "Pattern", "XObject"} {
+		var entries []string
+		for _, res := range r.resources {
+			if res.category == category {
+				entries = append(entries, fmt.Sprintf("/%s %d 0 R", res.name, res.object))
+			}
+		}
+		if len(entries) > 0 {
+			fmt.Fprintf(&resources, " /%s << %s >>", category, strings.Join(entries, " "))
+		}
+	}
+	resources.WriteString(" >>")
+	resourcesObject := add(resources.Bytes())
+
+	var kids []string
+	for _, page := range r.pages {
+		content := page.Bytes()
+		if page == r.page {
+			content = append(append([]byte(nil), content...), strings.Repeat("Q\n", len(r.clips))...)
+		}
+		contentObject := add(pdfStream("/Filter /FlateDecode", pdfDeflate(content)))
+		pageObject := add([]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources %d 0 R /Contents %d 0 R >>",
+			r.width, r.height, resourcesObject, contentObject)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObject))
+	}
+	objects[0] = []byte("<< /Type /Catalog /Pages 2 0 R >>")
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(object)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writePDFPath(b *bytes.Buffer, path raster.Path, closed []bool) {
+	var current *Vector
+	walkStroke(path, closed, func(op int, points []*Vector) {
+		switch op {
+		case 0:
+			fmt.Fprintf(b, "%s %s m\n", pdfNumber(points[0].X), pdfNumber(points[0].Y))
+		case 1:
+			fmt.Fprintf(b, "%s %s l\n", pdfNumber(points[0].X), pdfNumber(points[0].Y))
+		case 2:
+			// raise the quadratic segment to a cubic one
+			q, p := points[0], points[1]
+			c1 := current.Interpolate(q, 2.0/3)
+			c2 := p.Interpolate(q, 2.0/3)
+			fmt.Fprintf(b, "%s %s %s %s %s %s c\n", pdfNumber(c1.X), pdfNumber(c1.Y),
+				pdfNumber(c2.X), pdfNumber(c2.Y), pdfNumber(p.X), pdfNumber(p.Y))
+		case 3:
+			fmt.Fprintf(b, "%s %s %s %s %s %s c\n", pdfNumber(points[0].X), pdfNumber(points[0].Y),
+				pdfNumber(points[1].X), pdfNumber(points[1].Y), pdfNumber(points[2].X), pdfNumber(points[2].Y))
+		case 4:
+			b.WriteString("h\n")
+			return
+		}
+		current = points[len(points)-1]
+	})
+}
+
+// pdfFunction returns a function dictionary interpolating the stops over
+// the domain 0..1.
+func pdfFunction(s stops) string {
+	if len(s) == 0 {
+		s = stops{{0, color.Transparent}}
+	}
+	if s[0].pos > 0 {
+		s = append(stops{{0, s[0].color}}, s...)
+	}
+	if s[len(s)-1].pos < 1 {
+		s = append(s, stop{1, s[len(s)-1].color})
+	}
+	rgb := func(c color.Color) string {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		return fmt.Sprintf("[%s %s %s]", pdfNumber(float64(n.R)/255),
+			pdfNumber(float64(n.G)/255), pdfNumber(float64(n.B)/255))
+	}
+	exponential := func(c0, c1 color.Color) string {
+		return fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 %s /C1 %s /N 1 >>", rgb(c0), rgb(c1))
+	}
+	var functions, bounds, encode []string
+	for i := 1; i < len(s); i++ {
+		pos := s[i].pos
+		if pos < 0 {
+			pos = 0
+		} else if pos > 1 {
+			pos = 1
+		}
+		functions = append(functions, exponential(s[i-1].color, s[i].color))
+		if i < len(s)-1 {
+			bounds = append(bounds, pdfNumber(pos))
+		}
+		encode = append(encode, "0 1")
+	}
+	if len(functions) == 1 {
+		return functions[0]
+	}
+	return fmt.Sprintf("<< /FunctionType 3 /Domain [0 1] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		strings.Join(functions, " "), strings.Join(bounds, " "), strings.Join(encode, " "))
+}
+
+func pdfMatrix(m *Matrix) string {
+	return fmt.Sprintf("%s %s %s %s %s %s", pdfNumber(m.XX), pdfNumber(m.YX),
+		pdfNumber(m.XY), pdfNumber(m.YY), pdfNumber(m.X0), pdfNumber(m.Y0))
+}
+
+func pdfNumber(x float64) string {
+	s := strconv.FormatFloat(x, 'f', 4, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func pdfStream(dict string, data []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+func pdfDeflate(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
@@ -0,0 +1,201 @@
+package drawlib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pdfObjects splits an encoded PDF into its objects, checking the xref
+// table on the way. Flate streams are inflated in place.
+func pdfObjects(t *testing.T, data []byte) []string {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	var n int
+	if _, err := fmt.Sscanf(string(data[xref:]), "xref\n0 %d\n", &n); err != nil {
+		t.Fatalf("no xref table at %d: %v", xref, err)
+	}
+	entries := data[xref+len(fmt.Sprintf("xref\n0 %d\n", n)):]
+	objects := make([]string, n-1)
+	for i := range objects {
+		entry := string(entries[20*(i+1) : 20*(i+2)])
+		offset, _ := strconv.Atoi(entry[:10])
+		header := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("xref entry %d points at %q", i+1, data[offset:offset+10])
+		}
+		body := string(data[offset+len(header):])
+		body = body[:strings.Index(body, "\nendobj\n")]
+		if i := strings.Index(body, "\nstream\n"); i >= 0 && strings.Contains(body[:i], "/FlateDecode") {
+			stream := body[i+len("\nstream\n") : len(body)-len("\nendstream")]
+			r, err := zlib.NewReader(strings.NewReader(stream))
+			if err != nil {
+				t.Fatal(err)
+			}
+			inflated, _ := ioutil.ReadAll(r)
+			body = body[:i+len("\nstream\n")] + string(inflated)
+		}
+		objects[i] = body
+	}
+	return objects
+}
+
+// pdfPages returns the content streams of the pages in order.
+func pdfPages(t *testing.T, objects []string) []string {
+	t.Helper()
+	var pages []string
+	contents := regexp.MustCompile(`/Type /Page .*/Contents (\d+) 0 R`)
+	for _, object := range objects {
+		if m := contents.FindStringSubmatch(object); m != nil {
+			i, _ := strconv.Atoi(m[1])
+			content := objects[i-1]
+			pages = append(pages, content[strings.Index(content, "stream\n")+len("stream\n"):])
+		}
+	}
+	return pages
+}
+
+func encodePDF(t *testing.T, c *PDFCanvas) []string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := c.EncodePDF(&b); err != nil {
+		t.Fatal(err)
+	}
+	return pdfObjects(t, b.Bytes())
+}
+
+func TestPDFPages(t *testing.T) {
+	c := NewPDFCanvas(100, 50)
+	c.SetRGB(1, 0, 0)
+	c.MoveTo(10, 10)
+	c.LineTo(40, 10)
+	c.LineTo(40, 40)
+	c.ClosePath()
+	c.Fill()
+	c.NewPage()
+	c.DrawRectangle(0, 0, 50, 50)
+	c.Clip()
+	c.DrawCircle(25, 25, 10)
+	c.Stroke()
+	c.ResetClip()
+	im := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range im.Pix {
+		im.Pix[i] = 128
+	}
+	c.DrawImage(im, 60, 10)
+
+	objects := encodePDF(t, c)
+	pages := pdfPages(t, objects)
+	if len(pages) != 2 {
+		t.Fatalf("%d pages, want 2", len(pages))
+	}
+	if !strings.Contains(strings.Join(objects, "\n"), "/Count 2") {
+		t.Error("page tree does not count 2 pages")
+	}
+	for i, test := range []struct {
+		page int
+		ops  []string
+	}{
+		{0, []string{"1 0 0 -1 0 50 cm\n", "10 10 m\n", "40 40 l\n", "1 0 0 rg\n", "f\n"}},
+		{1, []string{"0 0 m\n", "W n\n", " c\n", "h\nS\n", "1 0 0 1 60 10 cm\n", "Do\n"}},
+	} {
+		for _, op := range test.ops {
+			if !strings.Contains(pages[test.page], op) {
+				t.Errorf("%d: page %d has no %q:\n%s", i, test.page+1, op, pages[test.page])
+			}
+		}
+	}
+	for i, page := range pages {
+		if q, Q := strings.Count(page, "q\n"), strings.Count(page, "Q\n"); q != Q {
+			t.Errorf("page %d saves the state %d times and restores it %d times", i+1, q, Q)
+		}
+	}
+	smask := false
+	for _, object := range objects {
+		smask = smask || strings.Contains(object, "/Subtype /Image") && strings.Contains(object, "/SMask")
+	}
+	if !smask {
+		t.Error("translucent image has no soft mask")
+	}
+}
+
+func TestPDFSave(t *testing.T) {
+	c := NewPDFCanvas(20, 20)
+	c.DrawRectangle(5, 5, 10, 10)
+	c.Fill()
+	path := filepath.Join(t.TempDir(), "out.pdf")
+	if err := c.SavePDF(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	c.EncodePDF(&b)
+	if !bytes.Equal(saved, b.Bytes()) {
+		t.Error("saved file differs from the encoded document")
+	}
+}
+
+func TestPDFBitmapText(t *testing.T) {
+	c := NewPDFCanvas(200, 100)
+	c.SetRGB(0, 0, 0)
+	c.DrawString("Hi", 20, 40)
+	objects := encodePDF(t, c)
+	size := regexp.MustCompile(`/Subtype /Image /Width (\d+) /Height (\d+)`)
+	found := false
+	for _, object := range objects {
+		if m := size.FindStringSubmatch(object); m != nil && !strings.Contains(object, "/DeviceGray") {
+			found = true
+			w, _ := strconv.Atoi(m[1])
+			h, _ := strconv.Atoi(m[2])
+			if w >= 50 || h >= 50 {
+				t.Errorf("text image is %dx%d, want it cropped to the text", w, h)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("text was not recorded as an image")
+	}
+	if page := pdfPages(t, objects)[0]; !strings.Contains(page, "Do\n") {
+		t.Errorf("page does not draw the text image:\n%s", page)
+	}
+}
+
+func TestPDFGradientAlpha(t *testing.T) {
+	for _, test := range []struct {
+		end     color.Color
+		shading bool
+	}{
+		{color.RGBA{0, 0, 255, 255}, true},
+		{color.RGBA{0, 0, 128, 128}, false},
+	} {
+		c := NewPDFCanvas(40, 40)
+		g := NewLinearGradient(0, 0, 40, 0)
+		g.AddColorStop(0, color.RGBA{255, 0, 0, 255})
+		g.AddColorStop(1, test.end)
+		c.SetFillStyle(g)
+		c.DrawRectangle(0, 0, 40, 40)
+		c.Fill()
+		all := strings.Join(encodePDF(t, c), "\n")
+		if got := strings.Contains(all, "/ShadingType 2"); got != test.shading {
+			t.Errorf("end %v: shading %v, want %v", test.end, got, test.shading)
+		}
+		if got := strings.Contains(all, "/SMask"); got == test.shading {
+			t.Errorf("end %v: soft mask %v, want %v", test.end, got, !test.shading)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package drawlib
+
+import (
+	"image"
+
+	"github.com/golang/freetype/raster"
+)
+
+// paintKey identifies a paint server defined by a recorder. Gradients
+// follow the canvas matrix and may change after use, so one gradient may
+// need a server per matrix and version.
+type paintKey struct {
+	pattern Pattern
+	matrix  Matrix
+	version int
+}
+
+// recorder receives every drawing operation of a Canvas in device space,
+// after the operation has been rasterized. It backs the vector outputs.
+type recorder interface {
+	fill(c *Canvas, path raster.Path, pattern Pattern)
+	stroke(c *Canvas, path raster.Path, closed []bool, pattern Pattern)
+	clip(c *Canvas, path raster.Path)
+	resetClip(c *Canvas)
+	clear(c *Canvas)
+	image(c *Canvas, im image.Image, m *Matrix)
+	text(c *Canvas, s string, x, y float64)
+}
+
+func walkPath(p raster.Path, f func(op int, points []*Vector)) {
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case 0, 1:
+			f(int(p[i]), []*Vector{
+				NewVector(Unfix(p[i+1]), Unfix(p[i+2])),
+			})
+			i += 4
+		case 2:
+			f(2, []*Vector{
+				NewVector(Unfix(p[i+1]), Unfix(p[i+2])),
+				NewVector(Unfix(p[i+3]), Unfix(p[i+4])),
+			})
+			i += 6
+		case 3:
+			f(3, []*Vector{
+				NewVector(Unfix(p[i+1]), Unfix(p[i+2])),
+				NewVector(Unfix(p[i+3]), Unfix(p[i+4])),
+				NewVector(Unfix(p[i+5]), Unfix(p[i+6])),
+			})
+			i += 8
+		default:
+			panic("bad path")
+		}
+	}
+}
+
+// walkStroke is walkPath reporting, as op 4 with no points, the close that
+// ends every subpath flagged in closed.
+func walkStroke(p raster.Path, closed []bool, f func(op int, points []*Vector)) {
+	n := 0
+	end := func() {
+		if n > 0 && n <= len(closed) && closed[n-1] {
+			f(4, nil)
+		}
+	}
+	walkPath(p, func(op int, points []*Vector) {
+		if op == 0 {
+			end()
+			n++
+		}
+		f(op, points)
+	})
+	end()
+}
+
+// cacheablePattern reports whether pattern can be used as a map key by the
+// recorders; user patterns may not be comparable.
+func cacheablePattern(pattern Pattern) bool {
+	switch pattern.(type) {
+	case *linearGradient, *radialGradient, *conicGradient, *surfacePattern:
+		return true
+	}
+	return false
+}
+
+func newPaintKey(c *Canvas, pattern Pattern) paintKey {
+	k := paintKey{pattern: pattern, matrix: *Identity()}
+	if g, ok := pattern.(gradientShape); ok {
+		k.matrix = *c.matrix
+		k.version = g.base().version
+	}
+	return k
+}
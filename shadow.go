@@ -0,0 +1,71 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// SetShadowColor sets the color of the shadow drawn beneath fills, strokes,
+// text and images. A shadow is drawn only when its color is not transparent
+// and its blur or offset is not zero.
+func (c *Canvas) SetShadowColor(col color.Color) *Canvas {
+	c.shadowColor = col
+	return c
+}
+
+// SetShadowOffset sets how far the shadow is moved from the shape, in
+// device pixels.
+func (c *Canvas) SetShadowOffset(x, y float64) *Canvas {
+	c.shadowX, c.shadowY = x, y
+	return c
+}
+
+// SetShadowBlur sets the blur of the shadow in device pixels. As with HTML
+// canvas, the standard deviation of the Gaussian blur is half of blur.
+func (c *Canvas) SetShadowBlur(blur float64) *Canvas {
+	c.shadowBlur = math.Max(blur, 0)
+	return c
+}
+
+func (c *Canvas) hasShadow() bool {
+	if _, _, _, a := c.shadowColor.RGBA(); a == 0 {
+		return false
+	}
+	return c.shadowBlur > 0 || c.shadowX != 0 || c.shadowY != 0
+}
+
+// drawShadow draws the shadow of the shape that render draws onto a
+// transparent layer, through the clip with the blend mode and operator.
+// bounds holds the shape in device space; the layers cover only it and the
+// blur around it.
+func (c *Canvas) drawShadow(bounds image.Rectangle, render func(layer *image.RGBA)) {
+	if !c.hasShadow() {
+		return
+	}
+	d := image.Pt(int(math.Round(c.shadowX)), int(math.Round(c.shadowY)))
+	b := bounds.Add(d).Inset(-blurReach(c.shadowBlur / 2)).Intersect(c.im.Bounds())
+	if b.Empty() {
+		return
+	}
+	layer := image.NewRGBA(b.Sub(d))
+	render(layer)
+
+	alpha := image.NewAlpha(b)
+	for i := range alpha.Pix {
+		alpha.Pix[i] = layer.Pix[i*4+3]
+	}
+	gaussianBlur(alpha.Pix, b.Dx(), b.Dy(), alpha.Stride, 1, c.shadowBlur/2)
+
+	r, g, bl, a := c.shadowColor.RGBA()
+	for i, m := range alpha.Pix {
+		m := uint32(m)
+		layer.Pix[i*4+0] = uint8(r * m / 255 >> 8)
+		layer.Pix[i*4+1] = uint8(g * m / 255 >> 8)
+		layer.Pix[i*4+2] = uint8(bl * m / 255 >> 8)
+		layer.Pix[i*4+3] = uint8(a * m / 255 >> 8)
+	}
+	layer.Rect = b
+	c.compositeLayer(layer)
+	c.recordLayer(layer)
+}
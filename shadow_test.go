@@ -0,0 +1,115 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestShadowOffset(t *testing.T) {
+	c := NewCanvas(60, 60)
+	c.Background(255)
+	c.SetShadowColor(color.Black)
+	c.SetShadowOffset(10, 10)
+	c.SetRGB(1, 0, 0)
+	c.DrawRectangle(10, 10, 10, 10)
+	c.Fill()
+	for _, test := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{15, 15, color.RGBA{255, 0, 0, 255}},
+		{25, 25, color.RGBA{0, 0, 0, 255}},
+		{35, 35, color.RGBA{255, 255, 255, 255}},
+		{5, 5, color.RGBA{255, 255, 255, 255}},
+	} {
+		if got := c.im.RGBAAt(test.x, test.y); got != test.want {
+			t.Errorf("pixel %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestShadowBlurReach(t *testing.T) {
+	c := NewCanvas(100, 100)
+	c.Background(255)
+	c.SetShadowColor(color.Black)
+	c.SetShadowBlur(16)
+	c.DrawRectangle(40, 40, 20, 20)
+	c.Fill()
+	if got := c.im.RGBAAt(63, 50); got.R == 255 {
+		t.Errorf("no blurred shadow just outside the shape: %v", got)
+	}
+	reach := blurReach(8)
+	if got := c.im.RGBAAt(61+reach+1, 50); got.R != 255 {
+		t.Errorf("shadow beyond the blur reach: %v", got)
+	}
+}
+
+func TestFilterLayerBounds(t *testing.T) {
+	blur := NewGaussianBlurFilter(3)
+	g := NewLinearGradient(20, 0, 30, 0)
+	g.AddColorStop(0, color.RGBA{255, 0, 0, 255})
+	g.AddColorStop(1, color.RGBA{0, 0, 255, 255})
+	got := NewCanvas(60, 60)
+	got.SetFilters(blur)
+	got.SetFillStyle(g)
+	got.DrawRectangle(20, 20, 10, 10)
+	got.Fill()
+
+	want := NewCanvas(60, 60)
+	want.SetFillStyle(g)
+	want.DrawRectangle(20, 20, 10, 10)
+	want.Fill()
+	want.ApplyFilters(want.im.Bounds(), blur)
+	if !bytes.Equal(got.im.Pix, want.im.Pix) {
+		t.Error("filtering on a bounded layer differs from filtering the canvas")
+	}
+}
+
+func TestShadowRecorded(t *testing.T) {
+	draw := func(c *Canvas) {
+		c.SetShadowColor(color.RGBA{0, 0, 0, 128})
+		c.SetShadowOffset(5, 5)
+		c.SetShadowBlur(4)
+		c.SetRGB(0, 1, 0)
+		c.DrawCircle(30, 30, 10)
+		c.Fill()
+	}
+	js, err := json.Marshal(RecordDisplayList(60, 60, draw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dl DisplayList
+	if err := json.Unmarshal(js, &dl); err != nil {
+		t.Fatal(err)
+	}
+	got := NewCanvas(60, 60)
+	got.DrawDisplayList(&dl)
+	want := NewCanvas(60, 60)
+	draw(want)
+	if !bytes.Equal(got.im.Pix, want.im.Pix) {
+		t.Error("replayed shadow differs")
+	}
+}
+
+func TestNineSliceSingleShadow(t *testing.T) {
+	im := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for i := range im.Pix {
+		im.Pix[i] = 255
+	}
+	c := NewCanvas(60, 60)
+	c.Background(255, 0, 0)
+	c.SetShadowColor(color.Black)
+	c.SetShadowOffset(-5, -5)
+	c.DrawNineSlice(im, Insets{3, 3, 3, 3}, 20, 20, 30, 30)
+	for _, p := range []image.Point{{21, 21}, {30, 21}, {35, 35}, {44, 44}} {
+		if got := c.im.RGBAAt(p.X, p.Y); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("pixel %v is %v, want white", p, got)
+		}
+	}
+	if got := c.im.RGBAAt(17, 17); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("shadow pixel is %v, want black", got)
+	}
+}
@@ -0,0 +1,73 @@
+package drawlib
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/ATTHDEV/shiny/driver"
+	"github.com/ATTHDEV/shiny/screen"
+)
+
+type (
+	shinyBackend struct{}
+	shinyWindow  struct {
+		screen.Window
+		buffer  screen.Buffer
+		texture screen.Texture
+	}
+)
+
+func (shinyBackend) Main(o WindowOptions, f func(Window)) error {
+	var err error
+	driver.Main(func(s screen.Screen) {
+		var w screen.Window
+		w, err = s.NewWindow(screen.NewWindowOptions(
+			screen.Title(o.Title),
+			screen.Dimensions(o.Width, o.Height),
+			screen.Location(o.X, o.Y),
+		))
+		if err != nil {
+			return
+		}
+		sw := &shinyWindow{Window: w}
+		sw.buffer, err = s.NewBuffer(image.Point{o.Width, o.Height})
+		if err != nil {
+			w.Release()
+			return
+		}
+		sw.texture, err = s.NewTexture(sw.buffer.Bounds().Max)
+		if err != nil {
+			sw.buffer.Release()
+			w.Release()
+			return
+		}
+		f(sw)
+	})
+	return err
+}
+
+func (w *shinyWindow) Fill(dr image.Rectangle, c color.Color) {
+	w.Window.Fill(dr, c, draw.Src)
+}
+
+func (w *shinyWindow) Present(src *image.RGBA, dr image.Rectangle) {
+	draw.Draw(w.buffer.RGBA(), w.buffer.Bounds(), src, image.ZP, draw.Src)
+	w.texture.Upload(image.ZP, w.buffer, w.buffer.Bounds())
+	w.Window.Scale(dr, w.texture, w.texture.Bounds(), draw.Src, nil)
+	w.Window.Publish()
+}
+
+func (w *shinyWindow) SetSize(width, height int) {
+	w.Window.SetDimention(int32(width), int32(height))
+}
+
+func (w *shinyWindow) SetLocation(x, y, width, height int) {
+	w.Window.MoveWindow(int32(x), int32(y), int32(width), int32(height))
+}
+
+func (w *shinyWindow) Release() {
+	w.texture.Release()
+	w.buffer.Release()
+	w.Window.Release()
+}
@@ -0,0 +1,314 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"io/ioutil"
+)
+
+type AnimationMode int
+
+const (
+	AnimationLoop AnimationMode = iota
+	AnimationOnce
+	AnimationPingPong
+)
+
+const defaultFrameDuration = 0.1
+
+type (
+	// SpriteFrame is one image of a sprite sheet. Rect is where it lies in
+	// the sheet. A frame trimmed by the packer is drawn at Offset inside a
+	// box of the original Size. Duration is in seconds, 0 if unknown.
+	SpriteFrame struct {
+		Name     string
+		Rect     image.Rectangle
+		Offset   image.Point
+		Size     image.Point
+		Duration float64
+	}
+
+	SpriteSheet struct {
+		Image  image.Image
+		Frames []*SpriteFrame
+		Clips  map[string]*SpriteClip
+	}
+
+	// SpriteClip is a named sequence of frames of a sheet. Durations, in
+	// seconds, override the durations of the frames; a frame without either
+	// lasts 0.1 seconds.
+	SpriteClip struct {
+		Frames    []int
+		Durations []float64
+		Mode      AnimationMode
+	}
+
+	AnimatedSprite struct {
+		Sheet   *SpriteSheet
+		clips   map[string]*SpriteClip
+		name    string
+		clip    *SpriteClip
+		index   int
+		step    int
+		elapsed float64
+		done    bool
+	}
+
+	spriteAtlas struct {
+		Frames json.RawMessage `json:"frames"`
+		Meta   struct {
+			FrameTags []struct {
+				Name      string `json:"name"`
+				From      int    `json:"from"`
+				To        int    `json:"to"`
+				Direction string `json:"direction"`
+			} `json:"frameTags"`
+		} `json:"meta"`
+	}
+	spriteAtlasFrame struct {
+		Filename string          `json:"filename"`
+		Frame    spriteAtlasRect `json:"frame"`
+		Rotated  bool            `json:"rotated"`
+		Source   spriteAtlasRect `json:"spriteSourceSize"`
+		Size     spriteAtlasRect `json:"sourceSize"`
+		Duration float64         `json:"duration"`
+	}
+	spriteAtlasRect struct {
+		X, Y, W, H int
+	}
+)
+
+// NewSpriteSheet cuts im into frames of frameWidth x frameHeight, row by
+// row from the top left. Partial frames at the right and bottom edges are
+// left out.
+func NewSpriteSheet(im image.Image, frameWidth, frameHeight int) *SpriteSheet {
+	sheet := &SpriteSheet{Image: im, Clips: map[string]*SpriteClip{}}
+	b := im.Bounds()
+	for y := b.Min.Y; y+frameHeight <= b.Max.Y; y += frameHeight {
+		for x := b.Min.X; x+frameWidth <= b.Max.X; x += frameWidth {
+			sheet.Frames = append(sheet.Frames, &SpriteFrame{
+				Rect: image.Rect(x, y, x+frameWidth, y+frameHeight),
+				Size: image.Pt(frameWidth, frameHeight),
+			})
+		}
+	}
+	return sheet
+}
+
+// LoadSpriteSheet loads a sheet image and its JSON atlas.
+func LoadSpriteSheet(imagePath, atlasPath string) (*SpriteSheet, error) {
+	im, err := LoadImage(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(atlasPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpriteSheet(im, data)
+}
+
+// ParseSpriteSheet reads a JSON atlas in the hash or array format written
+// by TexturePacker and Aseprite. Aseprite frame tags become clips. Frames
+// rotated by the packer are not supported.
+func ParseSpriteSheet(im image.Image, data []byte) (*SpriteSheet, error) {
+	var atlas spriteAtlas
+	if err := json.Unmarshal(data, &atlas); err != nil {
+		return nil, err
+	}
+	var frames []spriteAtlasFrame
+	if raw := bytes.TrimSpace(atlas.Frames); len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &frames); err != nil {
+			return nil, err
+		}
+	} else {
+		// the order of the frames matters to the tags, so the hash is read
+		// key by key instead of into a map
+		d := json.NewDecoder(bytes.NewReader(raw))
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			var f spriteAtlasFrame
+			if err := d.Decode(&f); err != nil {
+				return nil, err
+			}
+			f.Filename, _ = key.(string)
+			frames = append(frames, f)
+		}
+	}
+
+	sheet := &SpriteSheet{Image: im, Clips: map[string]*SpriteClip{}}
+	min := im.Bounds().Min
+	for _, f := range frames {
+		if f.Rotated {
+			return nil, errors.New("rotated sprite frames are not supported")
+		}
+		frame := &SpriteFrame{
+			Name:     f.Filename,
+			Rect:     image.Rect(f.Frame.X, f.Frame.Y, f.Frame.X+f.Frame.W, f.Frame.Y+f.Frame.H).Add(min),
+			Offset:   image.Pt(f.Source.X, f.Source.Y),
+			Size:     image.Pt(f.Size.W, f.Size.H),
+			Duration: f.Duration / 1000,
+		}
+		if frame.Size == (image.Point{}) {
+			frame.Size = frame.Rect.Size()
+		}
+		sheet.Frames = append(sheet.Frames, frame)
+	}
+	for _, tag := range atlas.Meta.FrameTags {
+		clip := &SpriteClip{}
+		for i := tag.From; i <= tag.To && i < len(sheet.Frames); i++ {
+			clip.Frames = append(clip.Frames, i)
+		}
+		switch tag.Direction {
+		case "reverse":
+			for i, j := 0, len(clip.Frames)-1; i < j; i, j = i+1, j-1 {
+				clip.Frames[i], clip.Frames[j] = clip.Frames[j], clip.Frames[i]
+			}
+		case "pingpong":
+			clip.Mode = AnimationPingPong
+		}
+		sheet.Clips[tag.Name] = clip
+	}
+	return sheet, nil
+}
+
+// Frame returns the index of the frame with name, or -1.
+func (s *SpriteSheet) Frame(name string) int {
+	for i, f := range s.Frames {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// DrawSprite draws frame of sheet with the top left of its box at x, y.
+func (c *Canvas) DrawSprite(sheet *SpriteSheet, frame int, x, y float64) *Canvas {
+	f := sheet.Frames[frame]
+	x += float64(f.Offset.X)
+	y += float64(f.Offset.Y)
+	return c.DrawImageRect(sheet.Image, f.Rect, x, y, float64(f.Rect.Dx()), float64(f.Rect.Dy()))
+}
+
+// NewAnimatedSprite returns a sprite playing the frames of sheet, with the
+// clips of the sheet. No clip plays until Play is called.
+func NewAnimatedSprite(sheet *SpriteSheet) *AnimatedSprite {
+	s := &AnimatedSprite{Sheet: sheet, clips: map[string]*SpriteClip{}}
+	for name, clip := range sheet.Clips {
+		s.clips[name] = clip
+	}
+	return s
+}
+
+func (s *AnimatedSprite) AddClip(name string, clip *SpriteClip) *AnimatedSprite {
+	s.clips[name] = clip
+	return s
+}
+
+// Play starts the clip with name from its first frame, unless it is
+// already playing. A finished clip starts over.
+func (s *AnimatedSprite) Play(name string) *AnimatedSprite {
+	if s.clip != nil && s.name == name && !s.done {
+		return s
+	}
+	s.name = name
+	s.clip = s.clips[name]
+	s.Restart()
+	return s
+}
+
+func (s *AnimatedSprite) Restart() *AnimatedSprite {
+	s.index, s.step, s.elapsed, s.done = 0, 1, 0, false
+	return s
+}
+
+// Clip returns the name of the clip playing.
+func (s *AnimatedSprite) Clip() string {
+	return s.name
+}
+
+// Finished reports whether a clip played with AnimationOnce has shown its
+// last frame for its whole duration.
+func (s *AnimatedSprite) Finished() bool {
+	return s.done
+}
+
+// Frame returns the index in the sheet of the frame to draw, or -1 when no
+// clip is playing.
+func (s *AnimatedSprite) Frame() int {
+	if s.clip == nil || len(s.clip.Frames) == 0 {
+		return -1
+	}
+	return s.clip.Frames[s.index]
+}
+
+// Update advances the animation by dt seconds, as passed to RenderLoop.
+func (s *AnimatedSprite) Update(dt float64) {
+	if s.clip == nil || len(s.clip.Frames) == 0 || s.done {
+		return
+	}
+	s.elapsed += dt
+	for {
+		d := s.duration()
+		if s.elapsed < d {
+			return
+		}
+		s.elapsed -= d
+		s.advance()
+		if s.done {
+			s.elapsed = 0
+			return
+		}
+	}
+}
+
+func (s *AnimatedSprite) duration() float64 {
+	var d float64
+	if s.index < len(s.clip.Durations) {
+		d = s.clip.Durations[s.index]
+	} else {
+		d = s.Sheet.Frames[s.clip.Frames[s.index]].Duration
+	}
+	if d <= 0 {
+		d = defaultFrameDuration
+	}
+	return d
+}
+
+func (s *AnimatedSprite) advance() {
+	n := len(s.clip.Frames)
+	switch s.clip.Mode {
+	case AnimationOnce:
+		if s.index == n-1 {
+			s.done = true
+		} else {
+			s.index++
+		}
+	case AnimationPingPong:
+		if n == 1 {
+			return
+		}
+		if next := s.index + s.step; next < 0 || next >= n {
+			s.step = -s.step
+		}
+		s.index += s.step
+	default:
+		s.index = (s.index + 1) % n
+	}
+}
+
+// DrawAnimatedSprite draws the current frame of s like DrawSprite.
+func (c *Canvas) DrawAnimatedSprite(s *AnimatedSprite, x, y float64) *Canvas {
+	if frame := s.Frame(); frame >= 0 {
+		c.DrawSprite(s.Sheet, frame, x, y)
+	}
+	return c
+}
@@ -0,0 +1,25 @@
+package drawlib
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPlayRestartsFinishedClip(t *testing.T) {
+	sheet := NewSpriteSheet(image.NewRGBA(image.Rect(0, 0, 30, 10)), 10, 10)
+	s := NewAnimatedSprite(sheet)
+	s.AddClip("once", &SpriteClip{Frames: []int{0, 1, 2}, Mode: AnimationOnce})
+	s.Play("once")
+	s.Update(0.15)
+	if s.Play("once"); s.Frame() != 1 {
+		t.Errorf("replaying a running clip moved to frame %d, want 1", s.Frame())
+	}
+	s.Update(1)
+	if !s.Finished() {
+		t.Fatal("clip did not finish")
+	}
+	s.Play("once")
+	if s.Finished() || s.Frame() != 0 {
+		t.Errorf("finished clip replayed at frame %d, finished %v", s.Frame(), s.Finished())
+	}
+}
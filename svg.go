@@ -0,0 +1,304 @@
+package drawlib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/freetype/raster"
+	"github.com/golang/freetype/truetype"
+)
+
+type (
+	// SVGCanvas is a Canvas that also records every drawing operation as an
+	// SVG element. Paths are written in device space, so they already carry
+	// the Matrix; text and images keep it as a transform attribute.
+	SVGCanvas struct {
+		*Canvas
+		svg *svgRecorder
+	}
+	svgRecorder struct {
+		width, height int
+		defs          bytes.Buffer
+		body          bytes.Buffer
+		clips         []string
+		paints        map[paintKey]string
+		nextID        int
+	}
+)
+
+func NewSVGCanvas(width, height int) *SVGCanvas {
+	r := &svgRecorder{
+		width:  width,
+		height: height,
+		paints: map[paintKey]string{},
+	}
+	c := NewCanvas(width, height)
+	c.recorder = r
+	return &SVGCanvas{Canvas: c, svg: r}
+}
+
+func (c *SVGCanvas) EncodeSVG(w io.Writer) error {
+	return c.svg.encode(w)
+}
+
+func (c *SVGCanvas) SaveSVG(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.svg.encode(file)
+}
+
+func (r *svgRecorder) encode(w io.Writer) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		r.width, r.height, r.width, r.height)
+	if r.defs.Len() > 0 {
+		b.WriteString("<defs>\n")
+		b.Write(r.defs.Bytes())
+		b.WriteString("</defs>\n")
+	}
+	b.Write(r.body.Bytes())
+	for range r.clips {
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</svg>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (r *svgRecorder) id(prefix string) string {
+	r.nextID++
+	return prefix + strconv.Itoa(r.nextID)
+}
+
+func (r *svgRecorder) fill(c *Canvas, path raster.Path, pattern Pattern) {
+	fmt.Fprintf(&r.body, `<path d="%s" fill=%s fill-rule="%s"%s/>`+"\n",
+		svgPathData(path, nil), r.paint(c, pattern, "fill"), svgFillRule(c.fillRule), svgBlendMode(c))
+}
+
+func (r *svgRecorder) stroke(c *Canvas, path raster.Path, closed []bool, pattern Pattern) {
+	var attrs strings.Builder
+	fmt.Fprintf(&attrs, ` stroke-width="%s"`, svgNumber(c.lineWidth))
+	switch c.lineCap {
+	case LineCapRound:
+		attrs.WriteString(` stroke-linecap="round"`)
+	case LineCapButt:
+		attrs.WriteString(` stroke-linecap="butt"`)
+	case LineCapSquare:
+		attrs.WriteString(` stroke-linecap="square"`)
+	}
+	switch c.lineJoin {
+	case LineJoinRound:
+		attrs.WriteString(` stroke-linejoin="round"`)
+	case LineJoinBevel:
+		attrs.WriteString(` stroke-linejoin="bevel"`)
+	case LineJoinMiter:
+		fmt.Fprintf(&attrs, ` stroke-linejoin="miter" stroke-miterlimit="%s"`, svgNumber(c.miterLimit))
+	}
+	if len(c.dashes) > 0 {
+		dashes := make([]string, len(c.dashes))
+		for i, d := range c.dashes {
+			dashes[i] = svgNumber(d)
+		}
+		fmt.Fprintf(&attrs, ` stroke-dasharray="%s"`, strings.Join(dashes, " "))
+		if c.dashOffset != 0 {
+			fmt.Fprintf(&attrs, ` stroke-dashoffset="%s"`, svgNumber(c.dashOffset))
+		}
+	}
+	fmt.Fprintf(&r.body, `<path d="%s" fill="none" stroke=%s%s%s/>`+"\n",
+		svgPathData(path, closed), r.paint(c, pattern, "stroke"), attrs.String(), svgBlendMode(c))
+}
+
+func (r *svgRecorder) clip(c *Canvas, path raster.Path) {
+	id := r.id("clip")
+	fmt.Fprintf(&r.defs, `<clipPath id="%s"><path d="%s" clip-rule="%s"/></clipPath>`+"\n",
+		id, svgPathData(path, nil), svgFillRule(c.fillRule))
+	fmt.Fprintf(&r.body, `<g clip-path="url(#%s)">`+"\n", id)
+	r.clips = append(r.clips, id)
+}
+
+func (r *svgRecorder) resetClip(c *Canvas) {
+	for range r.clips {
+		r.body.WriteString("</g>\n")
+	}
+	r.clips = nil
+}
+
+// clear replaces the whole canvas, so everything recorded so far is dropped
+// apart from the definitions and the groups of the active clip.
+func (r *svgRecorder) clear(c *Canvas) {
+	r.body.Reset()
+	for _, id := range r.clips {
+		fmt.Fprintf(&r.body, `<g clip-path="url(#%s)">`+"\n", id)
+	}
+	if _, _, _, a := c.clearSrc.C.RGBA(); a == 0 {
+		return
+	}
+	fmt.Fprintf(&r.body, `<rect x="0" y="0" width="%d" height="%d" fill=%s/>`+"\n",
+		r.width, r.height, svgColor(c.clearSrc.C, "fill"))
+}
+
+func (r *svgRecorder) image(c *Canvas, im image.Image, m *Matrix) {
+	b := im.Bounds()
+	fmt.Fprintf(&r.body, `<image transform="%s" x="%d" y="%d" width="%d" height="%d" xlink:href="%s"%s/>`+"\n",
+		svgMatrix(m), b.Min.X, b.Min.Y, b.Dx(), b.Dy(), svgImageData(im), svgBlendMode(c))
+}
+
+func (r *svgRecorder) text(c *Canvas, s string, x, y float64) {
+	family := "monospace"
+	if c.font != nil {
+		if name := c.font.Name(truetype.NameIDFontFamily); name != "" {
+			family = name
+		}
+	}
+	fmt.Fprintf(&r.body, `<text transform="%s" x="%s" y="%s" font-family="%s" font-size="%s" fill=%s%s>%s</text>`+"\n",
+		svgMatrix(c.matrix), svgNumber(x), svgNumber(y), html.EscapeString(family),
+		svgNumber(c.fontSize), svgColor(c.color, "fill"), svgBlendMode(c), html.EscapeString(s))
+}
+
+// paint returns the value of a fill or stroke attribute for pattern,
+// including the opening quote, defining a paint server when needed.
+func (r *svgRecorder) paint(c *Canvas, pattern Pattern, attr string) string {
+	if p, ok := pattern.(*solidPattern); ok {
+		return svgColor(p.color, attr)
+	}
+	key := newPaintKey(c, pattern)
+	if cacheablePattern(pattern) {
+		if id, ok := r.paints[key]; ok {
+			return `"url(#` + id + `)"`
+		}
+	}
+	var id string
+	switch p := pattern.(type) {
+	case *linearGradient:
+		id = r.id("gradient")
+		fmt.Fprintf(&r.defs, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s"%s>`+"\n",
+			id, svgNumber(p.x0), svgNumber(p.y0), svgNumber(p.x1), svgNumber(p.y1), svgGradient(c, &p.gradient))
+		r.writeStops(p.stops)
+		r.defs.WriteString("</linearGradient>\n")
+	case *radialGradient:
+		id = r.id("gradient")
+		fmt.Fprintf(&r.defs, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s" fx="%s" fy="%s" fr="%s"%s>`+"\n",
+			id, svgNumber(p.c1.x), svgNumber(p.c1.y), svgNumber(p.c1.r),
+			svgNumber(p.c0.x), svgNumber(p.c0.y), svgNumber(p.c0.r), svgGradient(c, &p.gradient))
+		r.writeStops(p.stops)
+		r.defs.WriteString("</radialGradient>\n")
+	case *surfacePattern:
+		id = r.id("pattern")
+		b := p.im.Bounds()
+		fmt.Fprintf(&r.defs, `<pattern id="%s" patternUnits="userSpaceOnUse" width="%d" height="%d"><image width="%d" height="%d" xlink:href="%s"/></pattern>`+"\n",
+			id, b.Dx(), b.Dy(), b.Dx(), b.Dy(), svgImageData(p.im))
+	default:
+		// unknown patterns are sampled over the whole canvas
+		id = r.id("pattern")
+		sampled := c.devicePattern(pattern)
+		im := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+		for y := 0; y < c.height; y++ {
+			for x := 0; x < c.width; x++ {
+				im.Set(x, y, sampled.ColorAt(x, y))
+			}
+		}
+		fmt.Fprintf(&r.defs, `<pattern id="%s" patternUnits="userSpaceOnUse" width="%d" height="%d"><image width="%d" height="%d" xlink:href="%s"/></pattern>`+"\n",
+			id, c.width, c.height, c.width, c.height, svgImageData(im))
+	}
+	if cacheablePattern(pattern) {
+		r.paints[key] = id
+	}
+	return `"url(#` + id + `)"`
+}
+
+// svgGradient returns the spreadMethod and gradientTransform attributes of
+// g drawn with the canvas matrix, the paths being in device space.
+func svgGradient(c *Canvas, g *gradient) string {
+	var attrs string
+	switch g.spread {
+	case SpreadRepeat:
+		attrs += ` spreadMethod="repeat"`
+	case SpreadReflect:
+		attrs += ` spreadMethod="reflect"`
+	}
+	if m := g.deviceMatrix(c.matrix); !isIdentity(m) {
+		attrs += ` gradientTransform="` + svgMatrix(m) + `"`
+	}
+	return attrs
+}
+
+func (r *svgRecorder) writeStops(stops stops) {
+	for _, s := range stops {
+		pos := s.pos
+		if pos < 0 {
+			pos = 0
+		} else if pos > 1 {
+			pos = 1
+		}
+		fmt.Fprintf(&r.defs, `<stop offset="%s" stop-color=%s/>`+"\n", svgNumber(pos), svgColor(s.color, "stop"))
+	}
+}
+
+func svgPathData(path raster.Path, closed []bool) string {
+	var b strings.Builder
+	walkStroke(path, closed, func(op int, points []*Vector) {
+		b.WriteString([]string{"M", "L", "Q", "C", "Z "}[op])
+		for _, p := range points {
+			b.WriteString(svgNumber(p.X))
+			b.WriteByte(' ')
+			b.WriteString(svgNumber(p.Y))
+			b.WriteByte(' ')
+		}
+	})
+	return strings.TrimSpace(b.String())
+}
+
+func svgFillRule(rule FillRule) string {
+	if rule == FillRuleEvenOdd {
+		return "evenodd"
+	}
+	return "nonzero"
+}
+
+// svgColor returns a quoted color followed by the matching opacity
+// attribute when the color is not opaque.
+func svgColor(c color.Color, attr string) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	s := fmt.Sprintf(`"#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A != 255 {
+		s += fmt.Sprintf(` %s-opacity="%s"`, attr, svgNumber(float64(n.A)/255))
+	}
+	return s
+}
+
+// svgBlendMode returns a style attribute with the canvas blend mode, or
+// nothing for normal blending.
+func svgBlendMode(c *Canvas) string {
+	if c.blendMode == BlendNormal {
+		return ""
+	}
+	return ` style="mix-blend-mode:` + c.blendMode.String() + `"`
+}
+
+func svgMatrix(m *Matrix) string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)",
+		svgNumber(m.XX), svgNumber(m.YX), svgNumber(m.XY),
+		svgNumber(m.YY), svgNumber(m.X0), svgNumber(m.Y0))
+}
+
+func svgNumber(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+func svgImageData(im image.Image) string {
+	var b bytes.Buffer
+	png.Encode(&b, im)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+}
@@ -0,0 +1,57 @@
+package drawlib
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVGGradientChangedAfterUse(t *testing.T) {
+	c := NewSVGCanvas(20, 20)
+	g := NewLinearGradient(0, 0, 20, 0)
+	g.AddColorStop(0, color.Black)
+	c.SetFillStyle(g)
+	c.DrawRectangle(0, 0, 10, 10)
+	c.Fill()
+	c.DrawRectangle(10, 0, 10, 10)
+	c.Fill()
+	g.AddColorStop(1, color.White)
+	c.DrawRectangle(0, 10, 10, 10)
+	c.Fill()
+	g.(TransformableGradient).SetSpread(SpreadReflect)
+	c.DrawRectangle(10, 10, 10, 10)
+	c.Fill()
+
+	var b bytes.Buffer
+	if err := c.EncodeSVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if n := strings.Count(out, "<linearGradient"); n != 3 {
+		t.Errorf("got %d gradients, want 3:\n%s", n, out)
+	}
+	if !strings.Contains(out, `spreadMethod="reflect"`) {
+		t.Errorf("missing the reflected gradient:\n%s", out)
+	}
+}
+
+func TestPDFGradientChangedAfterUse(t *testing.T) {
+	c := NewPDFCanvas(20, 20)
+	g := NewLinearGradient(0, 0, 20, 0)
+	g.AddColorStop(0, color.Black)
+	c.SetFillStyle(g)
+	c.DrawRectangle(0, 0, 10, 10)
+	c.Fill()
+	g.AddColorStop(1, color.White)
+	c.DrawRectangle(0, 10, 10, 10)
+	c.Fill()
+
+	var b bytes.Buffer
+	if err := c.EncodePDF(&b); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b.Bytes(), []byte("/ShadingType 2")); n != 2 {
+		t.Errorf("got %d shadings, want 2", n)
+	}
+}
@@ -0,0 +1,768 @@
+package drawlib
+
+import (
+	"encoding/xml"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+)
+
+type (
+	// SVG is a parsed SVG document. DrawSVG renders the supported subset:
+	// groups and nested svg elements with transforms, the basic shapes, path,
+	// text, solid and gradient paint, opacity and clip paths.
+	SVG struct {
+		Width, Height float64
+		root          *svgNode
+		ids           map[string]*svgNode
+	}
+	svgNode struct {
+		XMLName  xml.Name
+		Attrs    []xml.Attr `xml:",any,attr"`
+		Children []*svgNode `xml:",any"`
+		Text     string     `xml:",chardata"`
+		attrs    map[string]string
+	}
+	svgStyle struct {
+		fill, stroke               string
+		fillOpacity, strokeOpacity float64
+		opacity                    float64
+		strokeWidth                float64
+		fillRule                   FillRule
+		lineCap                    LineCap
+		lineJoin                   LineJoin
+		miterLimit                 float64
+		dashes                     []float64
+		dashOffset                 float64
+		fontSize                   float64
+		textAnchor                 string
+	}
+	// svgRenderer draws an SVG onto c. width and height are the size of the
+	// innermost viewport in user units, which percentages refer to.
+	svgRenderer struct {
+		c             *Canvas
+		svg           *SVG
+		width, height float64
+	}
+)
+
+func LoadSVG(path string) (*SVG, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseSVG(file)
+}
+
+func ParseSVG(r io.Reader) (*SVG, error) {
+	root := &svgNode{}
+	if err := xml.NewDecoder(r).Decode(root); err != nil {
+		return nil, err
+	}
+	svg := &SVG{root: root, ids: map[string]*svgNode{}}
+	var index func(n *svgNode)
+	index = func(n *svgNode) {
+		n.attrs = map[string]string{}
+		for _, a := range n.Attrs {
+			n.attrs[a.Name.Local] = a.Value
+		}
+		for _, decl := range strings.Split(n.attrs["style"], ";") {
+			if i := strings.Index(decl, ":"); i > 0 {
+				n.attrs[strings.TrimSpace(decl[:i])] = strings.TrimSpace(decl[i+1:])
+			}
+		}
+		if id := n.attrs["id"]; id != "" {
+			svg.ids[id] = n
+		}
+		for _, child := range n.Children {
+			index(child)
+		}
+	}
+	index(root)
+	vb := parseSVGNumbers(root.attrs["viewBox"])
+	svg.Width = svgLength(root.attrs["width"], 0)
+	svg.Height = svgLength(root.attrs["height"], 0)
+	if len(vb) == 4 {
+		if svg.Width == 0 {
+			svg.Width = vb[2]
+		}
+		if svg.Height == 0 {
+			svg.Height = vb[3]
+		}
+	}
+	return svg, nil
+}
+
+// DrawSVG renders svg with its top left corner at the origin of the current
+// matrix. The current path and clip are left as they were.
+func (c *Canvas) DrawSVG(svg *SVG) *Canvas {
+	saved := c.takePath()
+	c.Push()
+	r := &svgRenderer{c: c, svg: svg, width: svg.Width, height: svg.Height}
+	if vb := parseSVGNumbers(svg.root.attrs["viewBox"]); len(vb) == 4 && vb[2] > 0 && vb[3] > 0 {
+		c.matrix = svgViewBox(vb, svg.Width, svg.Height, svg.root.attrs["preserveAspectRatio"]).Multiply(*c.matrix)
+		r.width, r.height = vb[2], vb[3]
+	}
+	r.drawChildren(svg.root, svgStyle{
+		fill:          "black",
+		stroke:        "none",
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		opacity:       1,
+		strokeWidth:   1,
+		lineCap:       LineCapButt,
+		lineJoin:      LineJoinMiter,
+		miterLimit:    4,
+		fontSize:      16,
+		textAnchor:    "start",
+	})
+	c.Pop()
+	c.restorePath(saved)
+	return c
+}
+
+func (r *svgRenderer) drawChildren(n *svgNode, style svgStyle) {
+	for _, child := range n.Children {
+		r.draw(child, style)
+	}
+}
+
+func (r *svgRenderer) draw(n *svgNode, parent svgStyle) {
+	if n.attrs["display"] == "none" || n.attrs["visibility"] == "hidden" {
+		return
+	}
+	switch n.XMLName.Local {
+	case "defs", "clipPath", "linearGradient", "radialGradient", "title", "desc",
+		"metadata", "style", "symbol", "marker", "mask", "pattern", "filter":
+		return
+	}
+	c := r.c
+	style := parent.inherit(n)
+	c.Push()
+	defer c.Pop()
+	if t, ok := n.attrs["transform"]; ok {
+		c.matrix = parseSVGTransform(t).Multiply(*c.matrix)
+	}
+	if clip := svgURL(n.attrs["clip-path"]); clip != "" {
+		if node, ok := r.svg.ids[clip]; ok {
+			defer c.restoreClip(c.saveClip())
+			r.clip(node)
+		}
+	}
+	switch n.XMLName.Local {
+	case "svg", "g", "a":
+		if n != r.svg.root && n.XMLName.Local == "svg" {
+			r.viewport(n, style)
+			return
+		}
+		r.drawChildren(n, style)
+		return
+	case "text":
+		r.text(n, style)
+		return
+	}
+	if !r.shape(n) {
+		return
+	}
+	r.paint(style)
+}
+
+// viewport draws a nested svg element, which places its own viewport at x,
+// y in the parent's user space and may map a viewBox onto it.
+func (r *svgRenderer) viewport(n *svgNode, style svgStyle) {
+	c := r.c
+	w, h := r.width, r.height
+	a := func(name string, ref float64) float64 {
+		if _, ok := n.attrs[name]; !ok {
+			return ref
+		}
+		return svgLength(n.attrs[name], ref)
+	}
+	vw, vh := a("width", w), a("height", h)
+	if vw <= 0 || vh <= 0 {
+		return
+	}
+	c.matrix = c.matrix.Translate(svgLength(n.attrs["x"], w), svgLength(n.attrs["y"], h))
+	r.width, r.height = vw, vh
+	if vb := parseSVGNumbers(n.attrs["viewBox"]); len(vb) == 4 && vb[2] > 0 && vb[3] > 0 {
+		c.matrix = svgViewBox(vb, vw, vh, n.attrs["preserveAspectRatio"]).Multiply(*c.matrix)
+		r.width, r.height = vb[2], vb[3]
+	}
+	r.drawChildren(n, style)
+	r.width, r.height = w, h
+}
+
+// diagonal is the length that percentages of radii refer to.
+func (r *svgRenderer) diagonal() float64 {
+	return math.Sqrt((r.width*r.width + r.height*r.height) / 2)
+}
+
+// shape adds the geometry of a basic shape or path to the current path.
+func (r *svgRenderer) shape(n *svgNode) bool {
+	c := r.c
+	a := func(name string, ref float64) float64 {
+		return svgLength(n.attrs[name], ref)
+	}
+	w, h := r.width, r.height
+	switch n.XMLName.Local {
+	case "rect":
+		x, y, rw, rh := a("x", w), a("y", h), a("width", w), a("height", h)
+		if rw <= 0 || rh <= 0 {
+			return false
+		}
+		rx, ry := a("rx", w), a("ry", h)
+		if _, ok := n.attrs["ry"]; !ok {
+			ry = rx
+		}
+		if _, ok := n.attrs["rx"]; !ok {
+			rx = ry
+		}
+		rx = math.Min(rx, rw/2)
+		ry = math.Min(ry, rh/2)
+		if rx > 0 && ry > 0 {
+			c.NewSubPath()
+			c.MoveTo(x+rx, y)
+			c.LineTo(x+rw-rx, y)
+			c.DrawEllipticalArc(x+rw-rx, y+ry, rx, ry, -math.Pi/2, 0)
+			c.LineTo(x+rw, y+rh-ry)
+			c.DrawEllipticalArc(x+rw-rx, y+rh-ry, rx, ry, 0, math.Pi/2)
+			c.LineTo(x+rx, y+rh)
+			c.DrawEllipticalArc(x+rx, y+rh-ry, rx, ry, math.Pi/2, math.Pi)
+			c.LineTo(x, y+ry)
+			c.DrawEllipticalArc(x+rx, y+ry, rx, ry, math.Pi, 3*math.Pi/2)
+			c.ClosePath()
+		} else {
+			c.DrawRectangle(x, y, rw, rh)
+		}
+	case "circle":
+		if a("r", r.diagonal()) <= 0 {
+			return false
+		}
+		c.DrawCircle(a("cx", w), a("cy", h), a("r", r.diagonal()))
+	case "ellipse":
+		if a("rx", w) <= 0 || a("ry", h) <= 0 {
+			return false
+		}
+		c.DrawEllipse(a("cx", w), a("cy", h), a("rx", w), a("ry", h))
+	case "line":
+		c.MoveTo(a("x1", w), a("y1", h))
+		c.LineTo(a("x2", w), a("y2", h))
+	case "polyline", "polygon":
+		p := parseSVGNumbers(n.attrs["points"])
+		if len(p) < 4 {
+			return false
+		}
+		c.NewSubPath()
+		c.MoveTo(p[0], p[1])
+		for i := 2; i+1 < len(p); i += 2 {
+			c.LineTo(p[i], p[i+1])
+		}
+		if n.XMLName.Local == "polygon" {
+			c.ClosePath()
+		}
+	case "path":
+		c.DrawSVGPath(n.attrs["d"])
+	default:
+		return false
+	}
+	return true
+}
+
+func (r *svgRenderer) paint(style svgStyle) {
+	c := r.c
+	if p := r.pattern(style.fill, style.fillOpacity*style.opacity); p != nil {
+		c.fillPattern = p
+		c.fillRule = style.fillRule
+		c.FillPreserve()
+	}
+	if p := r.pattern(style.stroke, style.strokeOpacity*style.opacity); p != nil && style.strokeWidth > 0 {
+		m := c.matrix
+		scale := math.Sqrt(math.Abs(m.XX*m.YY - m.XY*m.YX))
+		c.strokePattern = p
+		c.lineWidth = style.strokeWidth * scale
+		c.lineCap = style.lineCap
+		c.lineJoin = style.lineJoin
+		c.miterLimit = style.miterLimit
+		c.dashes = nil
+		for _, d := range style.dashes {
+			c.dashes = append(c.dashes, d*scale)
+		}
+		c.dashOffset = style.dashOffset * scale
+		c.StrokePreserve()
+	}
+	c.ClearPath()
+}
+
+func (r *svgRenderer) text(n *svgNode, style svgStyle) {
+	c := r.c
+	var text func(n *svgNode) string
+	text = func(n *svgNode) string {
+		s := n.Text
+		for _, child := range n.Children {
+			s += text(child)
+		}
+		return s
+	}
+	s := strings.Join(strings.Fields(text(n)), " ")
+	if s == "" {
+		return
+	}
+	if c.font != nil {
+		c.fontFace = truetype.NewFace(c.font, &truetype.Options{
+			Size: style.fontSize,
+		})
+		c.fontHeight = style.fontSize * 72 / 96
+		c.fontSize = style.fontSize
+	}
+	x := parseSVGNumbers(n.attrs["x"])
+	y := parseSVGNumbers(n.attrs["y"])
+	var fx, fy, ax float64
+	if len(x) > 0 {
+		fx = x[0]
+	}
+	if len(y) > 0 {
+		fy = y[0]
+	}
+	switch style.textAnchor {
+	case "middle":
+		ax = 0.5
+	case "end":
+		ax = 1
+	}
+	fill := r.pattern(style.fill, style.fillOpacity*style.opacity)
+	stroke := r.pattern(style.stroke, style.strokeOpacity*style.opacity)
+	if stroke != nil && style.strokeWidth <= 0 {
+		stroke = nil
+	}
+	if p, ok := fill.(*solidPattern); ok && stroke == nil {
+		c.color = p.color
+		c.DrawStringAnchored(s, fx, fy, ax, 0)
+		return
+	}
+	if c.font != nil {
+		// outline the text so it can take gradients and strokes
+		w, _ := c.MeasureString(s)
+		c.AppendPath(pathFromRaster(c.stringPath(s, fx-ax*w, fy)).Transform(c.matrix.Invert()))
+		r.paint(style)
+		return
+	}
+	// the fallback font has no outlines, so draw in the main color of the
+	// fill, or of the stroke when there is no fill
+	paint := fill
+	if paint == nil {
+		paint = stroke
+	}
+	if col, ok := svgMainColor(paint); ok {
+		c.color = col
+		c.DrawStringAnchored(s, fx, fy, ax, 0)
+	}
+}
+
+// svgMainColor returns the color of a solid pattern or the first stop of a
+// gradient.
+func svgMainColor(p Pattern) (color.Color, bool) {
+	switch p := p.(type) {
+	case *solidPattern:
+		return p.color, true
+	case gradientShape:
+		if stops := p.base().stops; len(stops) > 0 {
+			return stops[0].color, true
+		}
+	}
+	return nil, false
+}
+
+// clip intersects the clip with the shapes of a clipPath element.
+func (r *svgRenderer) clip(n *svgNode) {
+	c := r.c
+	c.Push()
+	if t, ok := n.attrs["transform"]; ok {
+		c.matrix = parseSVGTransform(t).Multiply(*c.matrix)
+	}
+	for _, child := range n.Children {
+		c.Push()
+		if t, ok := child.attrs["transform"]; ok {
+			c.matrix = parseSVGTransform(t).Multiply(*c.matrix)
+		}
+		r.shape(child)
+		c.NewSubPath()
+		c.Pop()
+	}
+	c.fillRule = FillRuleWinding
+	if n.attrs["clip-rule"] == "evenodd" {
+		c.fillRule = FillRuleEvenOdd
+	}
+	c.Clip()
+	c.Pop()
+}
+
+// pattern returns the Pattern for a fill or stroke value, or nil for none.
+func (r *svgRenderer) pattern(paint string, opacity float64) Pattern {
+	if id := svgURL(paint); id != "" {
+		if n, ok := r.svg.ids[id]; ok {
+			return r.gradient(n, opacity)
+		}
+		return nil
+	}
+	col, ok := parseSVGColor(paint)
+	if !ok {
+		return nil
+	}
+	col.A = uint8(float64(col.A)*opacity + 0.5)
+	return NewSolidPattern(col)
+}
+
+func (r *svgRenderer) gradient(n *svgNode, opacity float64) Pattern {
+	c := r.c
+	kind := n.XMLName.Local
+	if kind != "linearGradient" && kind != "radialGradient" {
+		return nil
+	}
+	// attributes and stops may be inherited through href
+	attr := func(name string) (string, bool) {
+		for g, depth := n, 0; g != nil && depth < 8; depth++ {
+			if v, ok := g.attrs[name]; ok {
+				return v, true
+			}
+			g = r.svg.ids[strings.TrimPrefix(g.attrs["href"], "#")]
+		}
+		return "", false
+	}
+	stopNodes := n.Children
+	for g, depth := n, 0; g != nil && len(stopNodes) == 0 && depth < 8; depth++ {
+		g = r.svg.ids[strings.TrimPrefix(g.attrs["href"], "#")]
+		if g != nil {
+			stopNodes = g.Children
+		}
+	}
+
+	m := Identity()
+	if t, ok := attr("gradientTransform"); ok {
+		m = parseSVGTransform(t)
+	}
+	units, _ := attr("gradientUnits")
+	boundingBox := units != "userSpaceOnUse"
+	if boundingBox {
+		x0, y0, x1, y1 := c.CurrentPath().Bounds()
+		m = m.Multiply(Matrix{x1 - x0, 0, 0, y1 - y0, x0, y0})
+	}
+	value := func(name string, def float64) float64 {
+		v, ok := attr(name)
+		if !ok {
+			return def
+		}
+		if boundingBox && strings.HasSuffix(v, "%") {
+			return svgLength(v, 1)
+		}
+		if boundingBox {
+			f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return f
+		}
+		// user space percentages refer to the viewport
+		switch name {
+		case "y1", "y2", "cy", "fy":
+			return svgLength(v, r.height)
+		case "r", "fr":
+			return svgLength(v, r.diagonal())
+		}
+		return svgLength(v, r.width)
+	}
+
+	var g Gradient
+	w, h, d := 1.0, 1.0, 1.0
+	if !boundingBox {
+		w, h, d = r.width, r.height, r.diagonal()
+	}
+	if kind == "linearGradient" {
+		g = NewLinearGradient(value("x1", 0), value("y1", 0), value("x2", w), value("y2", 0))
+	} else {
+		cx, cy, radius := value("cx", w/2), value("cy", h/2), value("r", d/2)
+		fx, fy := value("fx", cx), value("fy", cy)
+		g = NewRadialGradient(fx, fy, value("fr", 0), cx, cy, radius)
+	}
+	t := g.(TransformableGradient)
+	// the gradient follows the canvas matrix when it is drawn
+	t.SetMatrix(m)
+	switch spread, _ := attr("spreadMethod"); spread {
+	case "repeat":
+		t.SetSpread(SpreadRepeat)
+	case "reflect":
+		t.SetSpread(SpreadReflect)
+	}
+	for _, s := range stopNodes {
+		if s.XMLName.Local != "stop" {
+			continue
+		}
+		offset := svgLength(s.attrs["offset"], 1)
+		col, ok := parseSVGColor(s.attrs["stop-color"])
+		if _, set := s.attrs["stop-color"]; !set {
+			col, ok = color.NRGBA{0, 0, 0, 255}, true
+		}
+		if !ok {
+			continue
+		}
+		a := 1.0
+		if v, ok := s.attrs["stop-opacity"]; ok {
+			a, _ = strconv.ParseFloat(v, 64)
+		}
+		col.A = uint8(float64(col.A)*a*opacity + 0.5)
+		g.AddColorStop(offset, col)
+	}
+	return g
+}
+
+func (parent svgStyle) inherit(n *svgNode) svgStyle {
+	s := parent
+	s.dashes = append([]float64(nil), parent.dashes...)
+	number := func(name string, v *float64) {
+		if a, ok := n.attrs[name]; ok {
+			if f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(a), "px"), 64); err == nil {
+				*v = f
+			}
+		}
+	}
+	if v, ok := n.attrs["fill"]; ok {
+		s.fill = v
+	}
+	if v, ok := n.attrs["stroke"]; ok {
+		s.stroke = v
+	}
+	number("fill-opacity", &s.fillOpacity)
+	number("stroke-opacity", &s.strokeOpacity)
+	number("stroke-width", &s.strokeWidth)
+	number("stroke-miterlimit", &s.miterLimit)
+	number("stroke-dashoffset", &s.dashOffset)
+	number("font-size", &s.fontSize)
+	opacity := 1.0
+	number("opacity", &opacity)
+	s.opacity *= opacity
+	switch n.attrs["fill-rule"] {
+	case "evenodd":
+		s.fillRule = FillRuleEvenOdd
+	case "nonzero":
+		s.fillRule = FillRuleWinding
+	}
+	switch n.attrs["stroke-linecap"] {
+	case "butt":
+		s.lineCap = LineCapButt
+	case "round":
+		s.lineCap = LineCapRound
+	case "square":
+		s.lineCap = LineCapSquare
+	}
+	switch n.attrs["stroke-linejoin"] {
+	case "round":
+		s.lineJoin = LineJoinRound
+	case "bevel":
+		s.lineJoin = LineJoinBevel
+	case "miter", "miter-clip", "arcs":
+		s.lineJoin = LineJoinMiter
+	}
+	if v, ok := n.attrs["stroke-dasharray"]; ok {
+		s.dashes = parseSVGNumbers(v)
+	}
+	if v, ok := n.attrs["text-anchor"]; ok {
+		s.textAnchor = v
+	}
+	return s
+}
+
+func parseSVGNumbers(s string) []float64 {
+	var result []float64
+	scanner := &svgPathScanner{s: s}
+	for {
+		scanner.skipSeparators()
+		if scanner.done() {
+			return result
+		}
+		v, err := scanner.number()
+		if err != nil {
+			return result
+		}
+		result = append(result, v)
+	}
+}
+
+// svgViewBox maps the viewBox vb onto a viewport of width w and height h,
+// keeping the aspect ratio as preserveAspectRatio par says.
+func svgViewBox(vb []float64, w, h float64, par string) *Matrix {
+	sx, sy := w/vb[2], h/vb[3]
+	align, meetOrSlice := "xMidYMid", "meet"
+	if fields := strings.Fields(par); len(fields) > 0 {
+		align = fields[0]
+		if len(fields) > 1 {
+			meetOrSlice = fields[1]
+		}
+	}
+	if align != "none" {
+		s := math.Min(sx, sy)
+		if meetOrSlice == "slice" {
+			s = math.Max(sx, sy)
+		}
+		sx, sy = s, s
+	}
+	tx, ty := -vb[0]*sx, -vb[1]*sy
+	if strings.Contains(align, "xMid") {
+		tx += (w - vb[2]*sx) / 2
+	} else if strings.Contains(align, "xMax") {
+		tx += w - vb[2]*sx
+	}
+	if strings.Contains(align, "YMid") {
+		ty += (h - vb[3]*sy) / 2
+	} else if strings.Contains(align, "YMax") {
+		ty += h - vb[3]*sy
+	}
+	return &Matrix{sx, 0, 0, sy, tx, ty}
+}
+
+// svgUnits are the absolute length units in user units, which are pixels.
+var svgUnits = map[string]float64{
+	"":   1,
+	"px": 1,
+	"pt": 4.0 / 3,
+	"pc": 16,
+	"in": 96,
+	"cm": 96 / 2.54,
+	"mm": 96 / 25.4,
+}
+
+// svgLength parses a length, resolving percentages against ref. Relative
+// units other than percentages are not supported and give 0.
+func svgLength(s string, ref float64) float64 {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexAny(s, "0123456789.") + 1
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0
+	}
+	unit := strings.ToLower(s[i:])
+	if unit == "%" {
+		return v * ref / 100
+	}
+	return v * svgUnits[unit]
+}
+
+func svgURL(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "url(") || !strings.HasSuffix(s, ")") {
+		return ""
+	}
+	s = strings.Trim(s[4:len(s)-1], ` '"`)
+	return strings.TrimPrefix(s, "#")
+}
+
+func parseSVGTransform(s string) *Matrix {
+	m := Identity()
+	for {
+		open := strings.Index(s, "(")
+		end := strings.Index(s, ")")
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], ", \t\n"))
+		p := parseSVGNumbers(s[open+1 : end])
+		n := len(p)
+		s = s[end+1:]
+		for len(p) < 6 {
+			p = append(p, 0)
+		}
+		var t *Matrix
+		switch name {
+		case "matrix":
+			t = &Matrix{p[0], p[1], p[2], p[3], p[4], p[5]}
+		case "translate":
+			t = Translate(p[0], p[1])
+		case "scale":
+			if n == 1 {
+				p[1] = p[0]
+			}
+			t = Scale(p[0], p[1])
+		case "rotate":
+			t = Translate(-p[1], -p[2]).Multiply(*Rotate(ToRadians(p[0]))).Multiply(*Translate(p[1], p[2]))
+		case "skewX":
+			t = Shear(math.Tan(ToRadians(p[0])), 0)
+		case "skewY":
+			t = Shear(0, math.Tan(ToRadians(p[0])))
+		default:
+			continue
+		}
+		m = t.Multiply(*m)
+	}
+}
+
+func parseSVGColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "" || s == "none" || s == "transparent":
+		return color.NRGBA{}, false
+	case strings.HasPrefix(s, "#"):
+		r, g, b, a := HexToRGBA(s)
+		return color.NRGBA{uint8(r), uint8(g), uint8(b), uint8(a)}, true
+	case strings.HasPrefix(s, "rgb"):
+		open, end := strings.Index(s, "("), strings.Index(s, ")")
+		if open < 0 || end < open {
+			return color.NRGBA{}, false
+		}
+		parts := strings.Split(s[open+1:end], ",")
+		if len(parts) < 3 {
+			parts = strings.Fields(s[open+1 : end])
+		}
+		if len(parts) < 3 {
+			return color.NRGBA{}, false
+		}
+		var v [4]float64
+		v[3] = 1
+		for i := 0; i < len(parts) && i < 4; i++ {
+			p := strings.TrimSpace(parts[i])
+			if i < 3 {
+				v[i] = svgLength(p, 255)
+			} else {
+				v[i] = svgLength(p, 1)
+			}
+		}
+		clamp := func(x float64) uint8 {
+			return uint8(math.Max(0, math.Min(255, x)) + 0.5)
+		}
+		return color.NRGBA{clamp(v[0]), clamp(v[1]), clamp(v[2]), clamp(v[3] * 255)}, true
+	}
+	col, ok := svgColorNames[s]
+	return col, ok
+}
+
+var svgColorNames = map[string]color.NRGBA{
+	"black":     {0, 0, 0, 255},
+	"silver":    {192, 192, 192, 255},
+	"gray":      {128, 128, 128, 255},
+	"grey":      {128, 128, 128, 255},
+	"white":     {255, 255, 255, 255},
+	"maroon":    {128, 0, 0, 255},
+	"red":       {255, 0, 0, 255},
+	"purple":    {128, 0, 128, 255},
+	"fuchsia":   {255, 0, 255, 255},
+	"magenta":   {255, 0, 255, 255},
+	"green":     {0, 128, 0, 255},
+	"lime":      {0, 255, 0, 255},
+	"olive":     {128, 128, 0, 255},
+	"yellow":    {255, 255, 0, 255},
+	"navy":      {0, 0, 128, 255},
+	"blue":      {0, 0, 255, 255},
+	"teal":      {0, 128, 128, 255},
+	"aqua":      {0, 255, 255, 255},
+	"cyan":      {0, 255, 255, 255},
+	"orange":    {255, 165, 0, 255},
+	"pink":      {255, 192, 203, 255},
+	"brown":     {165, 42, 42, 255},
+	"gold":      {255, 215, 0, 255},
+	"indigo":    {75, 0, 130, 255},
+	"violet":    {238, 130, 238, 255},
+	"darkgray":  {169, 169, 169, 255},
+	"darkgrey":  {169, 169, 169, 255},
+	"lightgray": {211, 211, 211, 255},
+	"lightgrey": {211, 211, 211, 255},
+}
@@ -0,0 +1,221 @@
+package drawlib
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func renderSVG(t *testing.T, w, h int, src string, setup func(c *Canvas)) *Canvas {
+	t.Helper()
+	svg, err := ParseSVG(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCanvas(w, h)
+	c.Background(255)
+	if setup != nil {
+		setup(c)
+	}
+	c.DrawSVG(svg)
+	return c
+}
+
+func rgbaAt(c *Canvas, x, y int) color.RGBA {
+	return c.im.RGBAAt(x, y)
+}
+
+func TestParseSVGSize(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		w, h float64
+	}{
+		{`<svg width="120" height="80"/>`, 120, 80},
+		{`<svg viewBox="0 0 30 40"/>`, 30, 40},
+		{`<svg width="10px" viewBox="0 0 30 40"/>`, 10, 40},
+		{`<svg width="12pt" height="1in"/>`, 16, 96},
+		{`<svg width="2.54cm" height="2em"/>`, 96, 0},
+	} {
+		svg, err := ParseSVG(strings.NewReader(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if svg.Width != test.w || svg.Height != test.h {
+			t.Errorf("%s: size %vx%v, want %vx%v", test.src, svg.Width, svg.Height, test.w, test.h)
+		}
+	}
+	if _, err := ParseSVG(strings.NewReader(`<svg><rect></svg>`)); err == nil {
+		t.Error("bad XML parsed without error")
+	}
+}
+
+func TestSVGShapes(t *testing.T) {
+	c := renderSVG(t, 60, 60, `<svg width="60" height="60">
+		<rect x="0" y="0" width="20" height="20" fill="#f00"/>
+		<g transform="translate(30 0)"><circle cx="10" cy="10" r="8" fill="blue"/></g>
+		<polygon points="0,30 20,30 20,50" style="fill:lime"/>
+		<rect x="30" y="30" width="20" height="20" fill="red" display="none"/>
+	</svg>`, nil)
+	for _, test := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{10, 10, color.RGBA{255, 0, 0, 255}},
+		{40, 10, color.RGBA{0, 0, 255, 255}},
+		{18, 40, color.RGBA{0, 255, 0, 255}},
+		{2, 48, color.RGBA{255, 255, 255, 255}},
+		{40, 40, color.RGBA{255, 255, 255, 255}},
+	} {
+		if got := rgbaAt(c, test.x, test.y); got != test.want {
+			t.Errorf("pixel %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestSVGViewBoxAspectRatio(t *testing.T) {
+	c := renderSVG(t, 200, 100, `<svg width="200" height="100" viewBox="0 0 100 100">
+		<rect width="100" height="100" fill="red"/>
+	</svg>`, nil)
+	if got := rgbaAt(c, 20, 50); got.G != 255 {
+		t.Errorf("letterbox pixel is %v, want white", got)
+	}
+	if got := rgbaAt(c, 100, 50); got.G != 0 {
+		t.Errorf("center pixel is %v, want red", got)
+	}
+}
+
+func TestSVGNestedViewport(t *testing.T) {
+	c := renderSVG(t, 60, 60, `<svg width="60" height="60">
+		<svg x="10" y="10" width="20" height="20" viewBox="0 0 10 10">
+			<rect width="50%" height="10" fill="red"/>
+		</svg>
+	</svg>`, nil)
+	for _, test := range []struct {
+		x, y int
+		red  bool
+	}{
+		{11, 11, true},
+		{19, 28, true},
+		{21, 15, false},
+		{5, 5, false},
+		{35, 35, false},
+	} {
+		if got := rgbaAt(c, test.x, test.y).G == 0; got != test.red {
+			t.Errorf("pixel %d,%d red: %v, want %v", test.x, test.y, got, test.red)
+		}
+	}
+}
+
+func TestSVGUserSpaceGradientPercentages(t *testing.T) {
+	c := renderSVG(t, 100, 50, `<svg width="100" height="50">
+		<defs>
+			<linearGradient id="g" gradientUnits="userSpaceOnUse" x1="0" y1="0%" x2="0" y2="100%">
+				<stop offset="0" stop-color="red"/>
+				<stop offset="1" stop-color="blue"/>
+			</linearGradient>
+		</defs>
+		<rect width="100" height="50" fill="url(#g)"/>
+	</svg>`, nil)
+	if got := rgbaAt(c, 50, 1); got.R < 240 {
+		t.Errorf("top pixel is %v, want red", got)
+	}
+	if got := rgbaAt(c, 50, 48); got.B < 240 {
+		t.Errorf("bottom pixel is %v, want blue", got)
+	}
+}
+
+func TestSVGRadialGradientPercentages(t *testing.T) {
+	c := renderSVG(t, 100, 100, `<svg width="100" height="100">
+		<radialGradient id="g" gradientUnits="userSpaceOnUse" cx="50%" cy="50%" r="25%">
+			<stop offset="0" stop-color="black"/>
+			<stop offset="1" stop-color="white"/>
+		</radialGradient>
+		<rect width="100" height="100" fill="url(#g)"/>
+	</svg>`, nil)
+	// the radius is 25% of the normalized diagonal, 100
+	if got := rgbaAt(c, 50, 37); got.R < 100 || got.R > 155 {
+		t.Errorf("pixel halfway out is %v, want mid gray", got)
+	}
+	if got := rgbaAt(c, 50, 20); got.R < 250 {
+		t.Errorf("pixel past the radius is %v, want white", got)
+	}
+}
+
+func TestSVGTextPaint(t *testing.T) {
+	src := `<svg width="120" height="40">
+		<linearGradient id="g"><stop offset="0" stop-color="red"/><stop offset="1" stop-color="blue"/></linearGradient>
+		<text x="5" y="30" font-size="24" fill="url(#g)">Hello</text>
+	</svg>`
+	stroked := `<svg width="120" height="40">
+		<text x="5" y="30" font-size="24" fill="none" stroke="red">Hello</text>
+	</svg>`
+	withFont := func(c *Canvas) {
+		if err := c.LoadFontFace("resource/ARIAL.TTF", 24); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, c := range map[string]*Canvas{
+		"gradient":          renderSVG(t, 120, 40, src, withFont),
+		"gradient, no font": renderSVG(t, 120, 40, src, nil),
+		"stroke":            renderSVG(t, 120, 40, stroked, withFont),
+		"stroke, no font":   renderSVG(t, 120, 40, stroked, nil),
+	} {
+		painted := 0
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 120; x++ {
+				if rgbaAt(c, x, y).G < 128 {
+					painted++
+				}
+			}
+		}
+		if painted == 0 {
+			t.Errorf("%s: no text drawn", name)
+		}
+	}
+}
+
+func TestSVGNestedClipRecorded(t *testing.T) {
+	svg, err := ParseSVG(strings.NewReader(`<svg width="40" height="40">
+		<clipPath id="a"><rect width="20" height="40"/></clipPath>
+		<clipPath id="b"><rect width="40" height="20"/></clipPath>
+		<g clip-path="url(#a)">
+			<g clip-path="url(#b)"><rect width="40" height="40" fill="red"/></g>
+			<rect y="20" width="40" height="20" fill="blue"/>
+		</g>
+		<rect x="25" y="25" width="10" height="10" fill="lime"/>
+	</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSVGCanvas(40, 40)
+	c.Background(255)
+	c.DrawRectangle(0, 0, 36, 40)
+	c.Clip()
+	c.DrawSVG(svg)
+	c.SetRGB(1, 1, 0)
+	c.DrawRectangle(36, 0, 4, 40)
+	c.Fill()
+	var b bytes.Buffer
+	if err := c.EncodeSVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	replayed := renderSVG(t, 40, 40, b.String(), nil)
+	for _, test := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{10, 10, color.RGBA{255, 0, 0, 255}},
+		{10, 30, color.RGBA{0, 0, 255, 255}},
+		{30, 10, color.RGBA{255, 255, 255, 255}},
+		{30, 30, color.RGBA{0, 255, 0, 255}},
+		{38, 30, color.RGBA{255, 255, 255, 255}},
+	} {
+		if got := rgbaAt(c.Canvas, test.x, test.y); got != test.want {
+			t.Errorf("canvas pixel %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+		if got := rgbaAt(replayed, test.x, test.y); got != test.want {
+			t.Errorf("recorded pixel %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
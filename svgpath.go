@@ -0,0 +1,339 @@
+package drawlib
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type svgPathScanner struct {
+	s   string
+	pos int
+}
+
+// DrawSVGPath adds the path described by SVG path data to the current path.
+// On a syntax error the path is built up to the bad command, as SVG renderers
+// do, and the error is returned.
+func (c *Canvas) DrawSVGPath(d string) error {
+	return drawSVGPath(c, d)
+}
+
+func drawSVGPath(b pathBuilder, d string) error {
+	s := &svgPathScanner{s: d}
+	var (
+		cmd                byte
+		x, y               float64
+		startX, startY     float64
+		ctrlX, ctrlY       float64
+		prev               byte
+		closed, hasSubPath bool
+	)
+	for {
+		s.skipSeparators()
+		if s.done() {
+			return nil
+		}
+		at := s.pos
+		if b := s.s[s.pos]; isSVGCommand(b) {
+			cmd = b
+			s.pos++
+		} else if cmd == 0 {
+			return fmt.Errorf("svg path must begin with a moveto at %d", at)
+		} else if cmd == 'Z' || cmd == 'z' {
+			return fmt.Errorf("unexpected %q in svg path at %d", b, at)
+		}
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = x, y
+		}
+		upper := cmd &^ 0x20
+		if !hasSubPath && upper != 'M' {
+			return fmt.Errorf("svg path must begin with a moveto at %d", at)
+		}
+		if closed && upper != 'M' && upper != 'Z' {
+			// a command after closepath starts a new subpath at the same point
+			b.moveTo(x, y)
+		}
+		closed = false
+		switch upper {
+		case 'M':
+			p, err := s.numbers(2)
+			if err != nil {
+				return err
+			}
+			x, y = ox+p[0], oy+p[1]
+			startX, startY = x, y
+			b.newSubPath()
+			b.moveTo(x, y)
+			hasSubPath = true
+			// further pairs are implicit lineto commands
+			if rel {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'L':
+			p, err := s.numbers(2)
+			if err != nil {
+				return err
+			}
+			x, y = ox+p[0], oy+p[1]
+			b.lineTo(x, y)
+		case 'H':
+			p, err := s.numbers(1)
+			if err != nil {
+				return err
+			}
+			x = ox + p[0]
+			b.lineTo(x, y)
+		case 'V':
+			p, err := s.numbers(1)
+			if err != nil {
+				return err
+			}
+			y = oy + p[0]
+			b.lineTo(x, y)
+		case 'C', 'S':
+			var x1, y1 float64
+			var p []float64
+			var err error
+			if upper == 'C' {
+				if p, err = s.numbers(6); err != nil {
+					return err
+				}
+				x1, y1 = ox+p[0], oy+p[1]
+				p = p[2:]
+			} else {
+				if p, err = s.numbers(4); err != nil {
+					return err
+				}
+				x1, y1 = x, y
+				if prev == 'C' || prev == 'S' {
+					x1, y1 = 2*x-ctrlX, 2*y-ctrlY
+				}
+			}
+			ctrlX, ctrlY = ox+p[0], oy+p[1]
+			x, y = ox+p[2], oy+p[3]
+			b.cubicTo(x1, y1, ctrlX, ctrlY, x, y)
+		case 'Q', 'T':
+			if upper == 'Q' {
+				p, err := s.numbers(4)
+				if err != nil {
+					return err
+				}
+				ctrlX, ctrlY = ox+p[0], oy+p[1]
+				x, y = ox+p[2], oy+p[3]
+			} else {
+				p, err := s.numbers(2)
+				if err != nil {
+					return err
+				}
+				if prev == 'Q' || prev == 'T' {
+					ctrlX, ctrlY = 2*x-ctrlX, 2*y-ctrlY
+				} else {
+					ctrlX, ctrlY = x, y
+				}
+				x, y = ox+p[0], oy+p[1]
+			}
+			b.quadraticTo(ctrlX, ctrlY, x, y)
+		case 'A':
+			p, err := s.arc()
+			if err != nil {
+				return err
+			}
+			x0, y0 := x, y
+			x, y = ox+p[5], oy+p[6]
+			for _, q := range arcToCubics(x0, y0, p[0], p[1], ToRadians(p[2]), p[3] != 0, p[4] != 0, x, y) {
+				b.cubicTo(q[0], q[1], q[2], q[3], q[4], q[5])
+			}
+		case 'Z':
+			b.closePath()
+			x, y = startX, startY
+			closed = true
+		}
+		prev = upper
+	}
+}
+
+func isSVGCommand(b byte) bool {
+	switch b {
+	case 'M', 'm', 'L', 'l', 'H', 'h', 'V', 'v', 'C', 'c', 'S', 's',
+		'Q', 'q', 'T', 't', 'A', 'a', 'Z', 'z':
+		return true
+	}
+	return false
+}
+
+func (s *svgPathScanner) done() bool {
+	return s.pos >= len(s.s)
+}
+
+func (s *svgPathScanner) skipSeparators() {
+	comma := false
+	for !s.done() {
+		switch s.s[s.pos] {
+		case ' ', '\t', '\n', '\r', '\f':
+		case ',':
+			if comma {
+				return
+			}
+			comma = true
+		default:
+			return
+		}
+		s.pos++
+	}
+}
+
+func (s *svgPathScanner) numbers(n int) ([]float64, error) {
+	result := make([]float64, n)
+	for i := range result {
+		s.skipSeparators()
+		v, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// arc reads the seven arguments of an arc command. The two flags are single
+// digits and may be written without separators.
+func (s *svgPathScanner) arc() ([]float64, error) {
+	p, err := s.numbers(3)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < 2; i++ {
+		s.skipSeparators()
+		if s.done() || (s.s[s.pos] != '0' && s.s[s.pos] != '1') {
+			return nil, fmt.Errorf("bad svg arc flag at %d", s.pos)
+		}
+		p = append(p, float64(s.s[s.pos]-'0'))
+		s.pos++
+	}
+	end, err := s.numbers(2)
+	if err != nil {
+		return nil, err
+	}
+	return append(p, end...), nil
+}
+
+func (s *svgPathScanner) number() (float64, error) {
+	start := s.pos
+	i := s.pos
+	if i < len(s.s) && (s.s[i] == '+' || s.s[i] == '-') {
+		i++
+	}
+	digits := false
+	for i < len(s.s) && s.s[i] >= '0' && s.s[i] <= '9' {
+		i++
+		digits = true
+	}
+	if i < len(s.s) && s.s[i] == '.' {
+		i++
+		for i < len(s.s) && s.s[i] >= '0' && s.s[i] <= '9' {
+			i++
+			digits = true
+		}
+	}
+	if !digits {
+		return 0, fmt.Errorf("expected number in svg path at %d", start)
+	}
+	if i < len(s.s) && (s.s[i] == 'e' || s.s[i] == 'E') {
+		j := i + 1
+		if j < len(s.s) && (s.s[j] == '+' || s.s[j] == '-') {
+			j++
+		}
+		if j < len(s.s) && s.s[j] >= '0' && s.s[j] <= '9' {
+			for j < len(s.s) && s.s[j] >= '0' && s.s[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	s.pos = i
+	return strconv.ParseFloat(s.s[start:i], 64)
+}
+
+// arcToCubics converts an SVG elliptical arc from x1, y1 to x2, y2 into
+// cubic Bézier segments of at most a quarter turn each, following the
+// endpoint to center conversion of the SVG specification. Each segment is
+// returned as its two control points and end point.
+func arcToCubics(x1, y1, rx, ry, phi float64, large, sweep bool, x2, y2 float64) [][6]float64 {
+	if x1 == x2 && y1 == y2 {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return [][6]float64{{x1, y1, x2, y2, x2, y2}}
+	}
+	sin, cos := math.Sincos(phi)
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p := cos*dx + sin*dy
+	y1p := -sin*dx + cos*dy
+
+	lambda := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry)
+	if lambda > 1 {
+		lambda = math.Sqrt(lambda)
+		rx *= lambda
+		ry *= lambda
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := 0.0
+	if num > 0 && den > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if large == sweep {
+		coef = -coef
+	}
+	cxp := coef * rx * y1p / ry
+	cyp := -coef * ry * x1p / rx
+	cx := cos*cxp - sin*cyp + (x1+x2)/2
+	cy := sin*cxp + cos*cyp + (y1+y2)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1p-cxp)/rx, (y1p-cyp)/ry)
+	delta := angle((x1p-cxp)/rx, (y1p-cyp)/ry, (-x1p-cxp)/rx, (-y1p-cyp)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	point := func(t float64) (float64, float64) {
+		st, ct := math.Sincos(t)
+		return cx + rx*ct*cos - ry*st*sin, cy + rx*ct*sin + ry*st*cos
+	}
+	derivative := func(t float64) (float64, float64) {
+		st, ct := math.Sincos(t)
+		return -rx*st*cos - ry*ct*sin, -rx*st*sin + ry*ct*cos
+	}
+	n := int(math.Ceil(math.Abs(delta)/(math.Pi/2) - 1e-9))
+	if n < 1 {
+		n = 1
+	}
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	result := make([][6]float64, n)
+	px, py := x1, y1
+	for i := 0; i < n; i++ {
+		t1 := theta + step*float64(i)
+		t2 := t1 + step
+		qx, qy := point(t2)
+		if i == n-1 {
+			qx, qy = x2, y2
+		}
+		d1x, d1y := derivative(t1)
+		d2x, d2y := derivative(t2)
+		result[i] = [6]float64{px + k*d1x, py + k*d1y, qx - k*d2x, qy - k*d2y, qx, qy}
+		px, py = qx, qy
+	}
+	return result
+}
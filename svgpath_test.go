@@ -0,0 +1,84 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSVGPathBounds(t *testing.T) {
+	for _, test := range []struct {
+		d              string
+		x0, y0, x1, y1 float64
+	}{
+		{"M10 10 H30 V30 H10 Z", 10, 10, 30, 30},
+		{"m10,10 h20 v20 h-20 z", 10, 10, 30, 30},
+		{"M0-1.5.5.5L3e1 2", 0, -1.5, 30, 2},
+		{"M0 0 10 0 10 10", 0, 0, 10, 10},
+		{"m5 5 5 0 0 5", 5, 5, 10, 10},
+		{"M0 0 A10 10 0 0 1 20 0", 0, -10, 20, 0},
+		{"M0 0 a10 10 0 0 0 20 0", 0, 0, 20, 10},
+		{"M0 0A10 10 0 1120 0", 0, -10, 20, 0},
+		{"M0 0 Q10 20 20 0", 0, 0, 20, 10},
+		{"M0 0 Q10 20 20 0 T40 0", 0, -10, 40, 10},
+		{"M0 0 C0 20 20 20 20 0", 0, 0, 20, 15},
+		{"M0 0 C0 20 20 20 20 0 S40 -20 40 0", 0, -15, 40, 15},
+		{"M0 0 L10 0 Z l0 10", 0, 0, 10, 10},
+	} {
+		p := NewPath()
+		if err := p.DrawSVGPath(test.d); err != nil {
+			t.Errorf("%q: %v", test.d, err)
+			continue
+		}
+		x0, y0, x1, y1 := p.Bounds()
+		got := []float64{x0, y0, x1, y1}
+		want := []float64{test.x0, test.y0, test.x1, test.y1}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 0.01 {
+				t.Errorf("%q: bounds %v, want %v", test.d, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestSVGPathErrors(t *testing.T) {
+	for _, d := range []string{
+		"L10 10",
+		"10 10",
+		"M10",
+		"M0 0 L",
+		"M0 0 X 1",
+		"M0 0 A1 1 0 2 1 2 2",
+		"M0 0 Z 5",
+		"M0 0 L1 .",
+	} {
+		if err := NewPath().DrawSVGPath(d); err == nil {
+			t.Errorf("%q: parsed without error", d)
+		}
+	}
+}
+
+func TestCanvasDrawSVGPath(t *testing.T) {
+	c := NewCanvas(40, 40)
+	c.Translate(5, 0)
+	if err := c.DrawSVGPath("M5 5 h20 v10 h-20 z M5 20 L25 20 25 30 X"); err == nil {
+		t.Error("bad path data drew without error")
+	}
+	c.SetRGB(1, 0, 0)
+	c.Fill()
+	for _, test := range []struct {
+		x, y   int
+		filled bool
+	}{
+		{20, 10, true},
+		{7, 10, false},
+		{32, 10, false},
+		{28, 28, true},
+		{12, 28, false},
+		{20, 35, false},
+	} {
+		if got := c.im.RGBAAt(test.x, test.y).A == 255; got != test.filled {
+			t.Errorf("pixel %d,%d filled %v, want %v", test.x, test.y, got, test.filled)
+		}
+	}
+}
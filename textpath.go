@@ -0,0 +1,51 @@
+package drawlib
+
+// DrawStringOnPath draws s along path with the current font face, each glyph
+// standing on the path and rotated to its direction at the glyph's middle.
+// offset is measured from the start of the path for AlignLeft, from its
+// middle for AlignCenter and back from its end for AlignRight. Glyphs whose
+// middle would fall off either end of the path are not drawn.
+func (c *Canvas) DrawStringOnPath(s string, path *Path, offset float64, align Align) *Canvas {
+	m := NewPathMeasure(path)
+	var advances []float64
+	width := 0.0
+	prev := rune(-1)
+	for _, r := range s {
+		if prev >= 0 {
+			width += Unfix(c.fontFace.Kern(prev, r))
+		}
+		a, _ := c.fontFace.GlyphAdvance(r)
+		advances = append(advances, Unfix(a))
+		width += Unfix(a)
+		prev = r
+	}
+
+	d := offset
+	switch align {
+	case AlignCenter:
+		d = (m.Length()-width)/2 + offset
+	case AlignRight:
+		d = m.Length() - width - offset
+	}
+	i := 0
+	prev = -1
+	for _, r := range s {
+		if prev >= 0 {
+			d += Unfix(c.fontFace.Kern(prev, r))
+		}
+		advance := advances[i]
+		mid := d + advance/2
+		if mid >= 0 && mid <= m.Length() {
+			x, y, angle := m.PointAt(mid)
+			c.Push()
+			c.Translate(x, y)
+			c.Rotate(angle)
+			c.DrawStringAnchored(string(r), -advance/2, 0, 0, 0)
+			c.Pop()
+		}
+		d += advance
+		prev = r
+		i++
+	}
+	return c
+}
@@ -0,0 +1,64 @@
+package drawlib
+
+import (
+	"image"
+	"testing"
+)
+
+// textOnPath draws s along the path from x0, y0 to x1, y1 with the 7 pixel
+// wide default face and returns the bounds of the ink.
+func textOnPath(s string, x0, y0, x1, y1, offset float64, align Align) image.Rectangle {
+	c := NewCanvas(100, 100)
+	c.SetRGB(0, 0, 0)
+	c.DrawStringOnPath(s, NewPath().MoveTo(x0, y0).LineTo(x1, y1), offset, align)
+	return paintedBounds(c.im)
+}
+
+func TestStringOnPathAlign(t *testing.T) {
+	for _, test := range []struct {
+		align  Align
+		offset float64
+		x      int
+	}{
+		{AlignLeft, 10, 10},
+		{AlignCenter, 0, 43},
+		{AlignCenter, 5, 48},
+		{AlignRight, 10, 76},
+	} {
+		// the two glyphs fill the cells x..x+14 standing on y 50
+		b := textOnPath("HH", 0, 50, 100, 50, test.offset, test.align)
+		if b.Min.X < test.x || b.Min.X > test.x+2 || b.Max.X > test.x+14 || b.Max.X < test.x+11 {
+			t.Errorf("align %d offset %v: ink across %d..%d, want within cells %d..%d",
+				test.align, test.offset, b.Min.X, b.Max.X, test.x, test.x+14)
+		}
+		if b.Max.Y > 51 || b.Min.Y < 37 {
+			t.Errorf("align %d offset %v: ink down %d..%d, want standing on 50", test.align, test.offset, b.Min.Y, b.Max.Y)
+		}
+	}
+}
+
+func TestStringOnPathRotates(t *testing.T) {
+	// along a path running down the glyphs lean right, their tops towards +x
+	b := textOnPath("HH", 50, 10, 50, 90, 20, AlignLeft)
+	if b.Min.Y < 30 || b.Max.Y > 44 || b.Min.X < 49 || b.Max.X > 63 {
+		t.Errorf("ink in %v, want within 50,30-63,44", b)
+	}
+	if b.Dy() <= b.Dx() {
+		t.Errorf("ink %dx%d, want the two glyphs stacked down the path", b.Dx(), b.Dy())
+	}
+}
+
+func TestStringOnPathSkipsEnds(t *testing.T) {
+	// on a path from x 30 to 50 the cells start at 20, 27, 34, 41 and 48;
+	// the first and last have their middles off the path
+	b := textOnPath("HHHHH", 30, 50, 50, 50, -10, AlignLeft)
+	if b.Min.X < 27 || b.Max.X > 48 {
+		t.Errorf("ink across %d..%d, want only the cells 27..48", b.Min.X, b.Max.X)
+	}
+	if b.Dx() < 15 {
+		t.Errorf("ink across %d..%d, want three glyphs", b.Min.X, b.Max.X)
+	}
+	if b := textOnPath("HH", 0, 50, 100, 50, 200, AlignLeft); !b.Empty() {
+		t.Errorf("text past the end drew ink in %v", b)
+	}
+}
@@ -52,6 +52,12 @@ func ImageToRGBA(src image.Image) *image.RGBA {
 	return dst
 }
 
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, dst.Bounds().Min, draw.Src)
+	return dst
+}
+
 func HexToRGBA(x string) (r, g, b, a int) {
 	x = strings.TrimPrefix(x, "#")
 	a = 255
@@ -93,12 +99,16 @@ func Unfix(x fixed.Int26_6) float64 {
 	return 0
 }
 
-func LoadFontFace(path string, points float64) (font.Face, error) {
+func LoadFont(path string) (*truetype.Font, error) {
 	fontBytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	f, err := truetype.Parse(fontBytes)
+	return truetype.Parse(fontBytes)
+}
+
+func LoadFontFace(path string, points float64) (font.Face, error) {
+	f, err := LoadFont(path)
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +25,45 @@ func CreateQuadraticBezier(x0, y0, x1, y1, x2, y2 float64) []*Vector {
 	if n < 4 {
 		n = 4
 	}
+	return quadraticBezier(x0, y0, x1, y1, x2, y2, n)
+}
+
+func CreateCubicBezier(x0, y0, x1, y1, x2, y2, x3, y3 float64) []*Vector {
+	l := (math.Hypot(x1-x0, y1-y0) +
+		math.Hypot(x2-x1, y2-y1) +
+		math.Hypot(x3-x2, y3-y2))
+	n := int(l + 0.5)
+	if n < 4 {
+		n = 4
+	}
+	return cubicBezier(x0, y0, x1, y1, x2, y2, x3, y3, n)
+}
+
+// flattenQuadratic returns points along the curve such that no chord
+// between neighbours strays further than half the tolerance from it. The
+// other half is left to arcs, which are approximated by quadratics first.
+func flattenQuadratic(x0, y0, x1, y1, x2, y2, tolerance float64) []*Vector {
+	// a chord over 1/n of the curve is off by at most |B''|/(8n²)
+	dd := math.Hypot(x0-2*x1+x2, y0-2*y1+y2)
+	return quadraticBezier(x0, y0, x1, y1, x2, y2, segmentCount(dd/4, tolerance/2)+1)
+}
+
+// flattenCubic is flattenQuadratic for a cubic curve.
+func flattenCubic(x0, y0, x1, y1, x2, y2, x3, y3, tolerance float64) []*Vector {
+	dd := math.Max(math.Hypot(x0-2*x1+x2, y0-2*y1+y2), math.Hypot(x1-2*x2+x3, y1-2*y2+y3))
+	return cubicBezier(x0, y0, x1, y1, x2, y2, x3, y3, segmentCount(dd*3/4, tolerance/2)+1)
+}
+
+// segmentCount returns the n for which deviation/n² is within tolerance.
+func segmentCount(deviation, tolerance float64) int {
+	if !(tolerance > 0) || math.IsInf(tolerance, 1) {
+		tolerance = defaultTolerance
+	}
+	n := math.Ceil(math.Sqrt(deviation / tolerance))
+	return int(math.Max(1, math.Min(n, 10000)))
+}
+
+func quadraticBezier(x0, y0, x1, y1, x2, y2 float64, n int) []*Vector {
 	d := float64(n) - 1
 	result := make([]*Vector, n)
 	for i := 0; i < n; i++ {
@@ -38,14 +77,7 @@ func CreateQuadraticBezier(x0, y0, x1, y1, x2, y2 float64) []*Vector {
 	return result
 }
 
-func CreateCubicBezier(x0, y0, x1, y1, x2, y2, x3, y3 float64) []*Vector {
-	l := (math.Hypot(x1-x0, y1-y0) +
-		math.Hypot(x2-x1, y2-y1) +
-		math.Hypot(x3-x2, y3-y2))
-	n := int(l + 0.5)
-	if n < 4 {
-		n = 4
-	}
+func cubicBezier(x0, y0, x1, y1, x2, y2, x3, y3 float64, n int) []*Vector {
 	d := float64(n) - 1
 	result := make([]*Vector, n)
 	for i := 0; i < n; i++ {
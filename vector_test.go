@@ -0,0 +1,57 @@
+package drawlib
+
+import (
+	"math"
+	"testing"
+)
+
+// circleSegments returns how many segments a circle of radius r on a
+// canvas scaled by scale is drawn with at tolerance.
+func circleSegments(r, scale, tolerance float64) int {
+	c := NewCanvas(100, 100)
+	c.SetTolerance(tolerance)
+	c.Scale(scale, scale)
+	c.DrawCircle(0, 0, r)
+	return len(c.path.segments)
+}
+
+func TestSegmentCountFollowsTolerance(t *testing.T) {
+	coarse, fine := circleSegments(50, 1, 1), circleSegments(50, 1, 0.001)
+	if coarse >= fine {
+		t.Errorf("%d segments at tolerance 1 and %d at 0.001, want fewer for the coarser", coarse, fine)
+	}
+	if got, want := circleSegments(5, 10, 0.1), circleSegments(50, 1, 0.1); got != want {
+		t.Errorf("%d segments for a circle scaled up 10 times, want %d as drawn at that size", got, want)
+	}
+	if small, large := circleSegments(5, 1, 0.1), circleSegments(500, 1, 0.1); small >= large {
+		t.Errorf("%d segments for radius 5 and %d for 500, want more for the larger", small, large)
+	}
+	for _, tolerance := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if got, want := circleSegments(50, 1, tolerance), circleSegments(50, 1, defaultTolerance); got != want {
+			t.Errorf("tolerance %v: %d segments, want %d as by default", tolerance, got, want)
+		}
+	}
+	if n := segmentCount(1e6, math.NaN()); n != 3163 {
+		t.Errorf("NaN tolerance gives %d segments, want the default 3163", n)
+	}
+}
+
+func TestFlattenedCircleWithinTolerance(t *testing.T) {
+	for _, tolerance := range []float64{1, 0.1, 0.01} {
+		p := NewPath().SetTolerance(tolerance).DrawCircle(0, 0, 100)
+		lines, _ := p.flatten(tolerance)
+		worst := 0.0
+		for _, line := range lines {
+			for i, v := range line {
+				worst = math.Max(worst, math.Abs(math.Hypot(v.X, v.Y)-100))
+				if i > 0 {
+					m := v.Interpolate(line[i-1], 0.5)
+					worst = math.Max(worst, math.Abs(math.Hypot(m.X, m.Y)-100))
+				}
+			}
+		}
+		if worst > tolerance {
+			t.Errorf("tolerance %v: strays %v from the circle", tolerance, worst)
+		}
+	}
+}